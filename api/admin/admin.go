@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/apierror"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/redact"
+)
+
+// Config describes the admin listener. It is separate from the catalog port
+// so that it can be kept off the public network.
+type Config struct {
+	Enabled bool   `yaml:"enabled"`
+	Host    string `yaml:"host"`
+	Port    int    `yaml:"port"`
+	// Token is the bearer token required on every admin request.
+	Token string `yaml:"token"`
+}
+
+// Handler serves operational endpoints that are not part of the Iceberg REST
+// specification.
+type Handler struct {
+	readOnly *middleware.ReadOnly
+	config   func() any
+	redactor *redact.Redactor
+}
+
+type Option func(*Handler)
+
+// WithConfig exposes cfg, with secrets redacted, as the effective config.
+func WithConfig(cfg any) Option {
+	return WithConfigSource(func() any { return cfg })
+}
+
+// WithConfigSource exposes the config returned by source, so that the
+// effective config follows reloads.
+func WithConfigSource(source func() any) Option {
+	return func(h *Handler) {
+		h.config = source
+	}
+}
+
+// WithRedactor sets the redactor applied to the effective config. Without it
+// only the built-in sensitive keys are masked.
+func WithRedactor(r *redact.Redactor) Option {
+	return func(h *Handler) {
+		if r != nil {
+			h.redactor = r
+		}
+	}
+}
+
+func NewHandler(readOnly *middleware.ReadOnly, opts ...Option) *Handler {
+	h := &Handler{
+		readOnly: readOnly,
+		redactor: redact.Default(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *Handler) GetReadOnly(c *gin.Context) {
+	c.JSON(http.StatusOK, h.readOnly.Get())
+}
+
+func (h *Handler) SetReadOnly(c *gin.Context) {
+	var req middleware.ReadOnlyConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.WriteBindError(c, err)
+		return
+	}
+
+	h.readOnly.Set(req)
+	c.JSON(http.StatusOK, req)
+}
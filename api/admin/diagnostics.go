@@ -0,0 +1,136 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	runtimepprof "runtime/pprof"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/apierror"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is set at build time with
+// -ldflags "-X github.com/xixipi-lining/iceberg-rest-catalog/api/admin.Version=...".
+var Version = "dev"
+
+type LogLevelResponse struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+}
+
+type SetLogLevelRequest struct {
+	// Component is empty to change the global level.
+	Component string `json:"component,omitempty"`
+	// Level is a level name from trace to disabled. An empty level removes
+	// the override of Component, and is rejected without one.
+	Level string `json:"level"`
+}
+
+type BuildInfoResponse struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"go-version"`
+	Module    string `json:"module,omitempty"`
+	Revision  string `json:"revision,omitempty"`
+	BuildTime string `json:"build-time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+func (h *Handler) GetLogLevel(c *gin.Context) {
+	level, components := logger.Levels()
+	c.JSON(http.StatusOK, LogLevelResponse{Level: level, Components: components})
+}
+
+func (h *Handler) SetLogLevel(c *gin.Context) {
+	var req SetLogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.WriteBindError(c, err)
+		return
+	}
+
+	if req.Level == "" && req.Component != "" {
+		logger.ResetLevel(req.Component)
+	} else if err := logger.SetLevel(req.Component, req.Level); err != nil {
+		errModel := apierror.ErrBadRequest
+		errModel.Message = err.Error()
+		c.JSON(http.StatusBadRequest, apierror.NewResponse(c, errModel))
+		return
+	}
+
+	h.GetLogLevel(c)
+}
+
+func (h *Handler) BuildInfo(c *gin.Context) {
+	resp := BuildInfoResponse{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		resp.Module = info.Main.Path
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				resp.Revision = setting.Value
+			case "vcs.time":
+				resp.BuildTime = setting.Value
+			case "vcs.modified":
+				resp.Modified = setting.Value == "true"
+			}
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetConfig returns the effective config with sensitive values masked. It
+// round-trips through YAML so that keys match the config file.
+func (h *Handler) GetConfig(c *gin.Context) {
+	var config any
+	if h.config != nil {
+		config = h.config()
+	}
+	if config == nil {
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
+
+	raw, err := yaml.Marshal(config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
+		return
+	}
+	var tree map[string]any
+	if err := yaml.Unmarshal(raw, &tree); err != nil {
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, h.redactor.Tree(tree))
+}
+
+// Goroutines dumps the stacks of all goroutines in the panic format.
+func (h *Handler) Goroutines(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Status(http.StatusOK)
+	runtimepprof.Lookup("goroutine").WriteTo(c.Writer, 2)
+}
+
+// Pprof serves the net/http/pprof handlers under /debug/pprof.
+func (h *Handler) Pprof(c *gin.Context) {
+	switch name := c.Param("name"); name {
+	case "":
+		pprof.Index(c.Writer, c.Request)
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,142 @@
+// Package apierror holds the error bodies the API answers with, in the shape
+// of the Iceberg REST spec's ErrorModel.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Response struct {
+	Error Model `json:"error"`
+}
+
+type Model struct {
+	Message   string `json:"message"`
+	Type      string `json:"type"`
+	Code      int    `json:"code"`
+	RequestID string `json:"request-id,omitempty"`
+}
+
+// NewResponse wraps err and tags it with the request ID so that client
+// reports can be matched with server logs.
+func NewResponse(c *gin.Context, err Model) Response {
+	err.RequestID = c.GetString("requestID")
+	return Response{Error: err}
+}
+
+// WriteBindError answers a request whose body could not be decoded:
+// 413 when it is over the limit set by middleware.BodyLimit, 400 otherwise.
+func WriteBindError(c *gin.Context, err error) {
+	if tooLarge := new(http.MaxBytesError); errors.As(err, &tooLarge) {
+		// The rest of the body is not read, so the connection can't be reused.
+		c.Header("Connection", "close")
+		c.JSON(http.StatusRequestEntityTooLarge, NewResponse(c, ErrRequestTooLarge))
+		return
+	}
+	c.JSON(http.StatusBadRequest, NewResponse(c, ErrBadRequest))
+}
+
+var ErrInternalServerError = Model{
+	Message: "Internal Server Error",
+	Type:    "InternalServerError",
+	Code:    http.StatusInternalServerError,
+}
+
+var ErrBadRequest = Model{
+	Message: "Malformed request",
+	Type:    "BadRequestException",
+	Code:    http.StatusBadRequest,
+}
+
+var ErrUnsupportedEncoding = Model{
+	Message: "Unsupported Content-Encoding, use gzip or zstd",
+	Type:    "UnsupportedMediaTypeException",
+	Code:    http.StatusUnsupportedMediaType,
+}
+
+var ErrRequestTooLarge = Model{
+	Message: "Request body exceeds the server's limit",
+	Type:    "RequestTooLargeException",
+	Code:    http.StatusRequestEntityTooLarge,
+}
+
+var ErrNamespaceNotFound = Model{
+	Message: "The given namespace does not exist",
+	Type:    "NoSuchNamespaceException",
+	Code:    http.StatusNotFound,
+}
+
+var ErrNamespaceAlreadyExists = Model{
+	Message: "The given namespace already exists",
+	Type:    "AlreadyExistsException",
+	Code:    http.StatusConflict,
+}
+
+var ErrNamespaceNotEmpty = Model{
+	Message: "The given namespace is not empty",
+	Type:    "NamespaceNotEmptyException",
+	Code:    http.StatusConflict,
+}
+
+var ErrUnprocessableEntityDuplicateKey = Model{
+	Message: "The request cannot be processed as there is a key present multiple times",
+	Type:    "UnprocessableEntityException",
+	Code:    http.StatusUnprocessableEntity,
+}
+
+var ErrNotImplemented = Model{
+	Message: "Not Implemented",
+	Type:    "NotImplementedException",
+	Code:    http.StatusNotImplemented,
+}
+
+var ErrTableAlreadyExists = Model{
+	Message: "The given table already exists",
+	Type:    "AlreadyExistsException",
+	Code:    http.StatusConflict,
+}
+
+var ErrTableNotFound = Model{
+	Message: "The given table does not exist",
+	Type:    "NoSuchTableException",
+	Code:    http.StatusNotFound,
+}
+
+var ErrUnauthorized = Model{
+	Message: "Missing or invalid bearer token",
+	Type:    "NotAuthorizedException",
+	Code:    http.StatusUnauthorized,
+}
+
+var ErrTooManyRequests = Model{
+	Message: "Rate limit exceeded, retry after the interval given by Retry-After",
+	Type:    "TooManyRequestsException",
+	Code:    http.StatusTooManyRequests,
+}
+
+var ErrReadOnly = Model{
+	Message: "The catalog is in read-only mode, retry later",
+	Type:    "ServiceUnavailableException",
+	Code:    http.StatusServiceUnavailable,
+}
+
+var ErrShuttingDown = Model{
+	Message: "The server is shutting down, retry against another instance",
+	Type:    "ServiceUnavailableException",
+	Code:    http.StatusServiceUnavailable,
+}
+
+var ErrCommitFailed = Model{
+	Message: "Commit failed because the table was modified concurrently or a requirement no longer holds, refresh and try again",
+	Type:    "CommitFailedException",
+	Code:    http.StatusConflict,
+}
+
+var ErrCommitQueueBusy = Model{
+	Message: "Too many commits are waiting for this table, retry later",
+	Type:    "ServiceUnavailableException",
+	Code:    http.StatusServiceUnavailable,
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/apierror"
+	"github.com/xixipi-lining/iceberg-rest-catalog/audit"
+)
+
+func (h *CatalogHandler) newAuditEvent(c *gin.Context, operation string, namespace []string, tableName string) *audit.Event {
+	return &audit.Event{
+		Time:      time.Now().UTC(),
+		RequestID: c.GetString("requestID"),
+		Principal: c.GetString("principal"),
+		Operation: operation,
+		Namespace: namespace,
+		Table:     tableName,
+	}
+}
+
+// recordAudit is deferred by mutating handlers so the event carries the final
+// response status.
+func (h *CatalogHandler) recordAudit(c *gin.Context, event *audit.Event) {
+	if h.auditSink == nil {
+		return
+	}
+
+	event.Status = c.Writer.Status()
+	event.Outcome = audit.OutcomeSuccess
+	if event.Status >= http.StatusBadRequest {
+		event.Outcome = audit.OutcomeFailure
+	}
+
+	// The mutation happened even if the client has gone since, so its event
+	// must not be lost to the request being cancelled.
+	if err := h.auditSink.Record(context.WithoutCancel(c.Request.Context()), *event); err != nil {
+		getLogger(c).Errorf("failed to record audit event: %s", err)
+	}
+}
+
+func summarizeUpdates(updates table.Updates) []string {
+	summary := make([]string, 0, len(updates))
+	for _, u := range updates {
+		summary = append(summary, u.Action())
+	}
+	return summary
+}
+
+func (h *CatalogHandler) ListAuditEvents(c *gin.Context) {
+	log := getLogger(c)
+
+	if h.auditSink == nil {
+		c.JSON(http.StatusNotImplemented, apierror.NewResponse(c, apierror.ErrNotImplemented))
+		return
+	}
+
+	var req ListAuditEventsRequest
+	if err := c.BindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, apierror.NewResponse(c, apierror.ErrBadRequest))
+		return
+	}
+
+	filter := audit.Filter{
+		Table: req.Table,
+		Since: req.Since,
+		Until: req.Until,
+	}
+	if req.Namespace != nil {
+		filter.Namespace = strings.Split(*req.Namespace, namespaceSeparator)
+	}
+	if req.Limit != nil {
+		filter.Limit = *req.Limit
+	}
+
+	events, err := h.auditSink.Query(c.Request.Context(), filter)
+	if err != nil {
+		log.Errorf("failed to query audit events: %s", err)
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, ListAuditEventsResponse{
+		Events: events,
+	})
+}
+
+// previousMetadataLocation returns the newest entry of the metadata log.
+func previousMetadataLocation(md table.Metadata) string {
+	var previous string
+	for entry := range md.PreviousFiles() {
+		previous = entry.MetadataFile
+	}
+	return previous
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/phases"
+	"github.com/xixipi-lining/iceberg-rest-catalog/commit"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+	"github.com/xixipi-lining/iceberg-rest-catalog/metrics"
+)
+
+const (
+	// CommitAttemptsHeader reports how many attempts a commit took when
+	// server side retries are enabled.
+	CommitAttemptsHeader = "X-Iceberg-Commit-Attempts"
+	// CommitBatchHeader reports how many queued commits were applied together
+	// with this one.
+	CommitBatchHeader = "X-Iceberg-Commit-Batch"
+)
+
+// commitTable commits the update, through the per-table commit queue if it
+// is enabled.
+func (h *CatalogHandler) commitTable(c *gin.Context, base *table.Table, reqs []table.Requirement, updates []table.Update) (table.Metadata, string, error) {
+	log := getLogger(c)
+
+	start := time.Now()
+	single := func(ctx context.Context) commit.Result {
+		return h.retryCommit(ctx, log, base, reqs, updates)
+	}
+
+	var res commit.Result
+	if h.commitQueue != nil {
+		res = h.commitQueue.Submit(c.Request.Context(), commit.Request{
+			Base:         base,
+			Requirements: reqs,
+			Updates:      updates,
+			Commit:       single,
+		})
+	} else {
+		res = single(c.Request.Context())
+	}
+	// The wait for the table's turn isn't backend time.
+	phases.Add(c, phases.Queue, res.Waited)
+	phases.Add(c, phases.Backend, time.Since(start)-res.Waited)
+
+	if h.commitRetry.Enabled && res.Attempts > 0 {
+		c.Header(CommitAttemptsHeader, strconv.Itoa(res.Attempts))
+	}
+	if res.Batched > 1 {
+		c.Header(CommitBatchHeader, strconv.Itoa(res.Batched))
+	}
+	return res.Metadata, res.Location, res.Err
+}
+
+// retryCommit commits the update and, if retries are enabled, rebases and
+// retries it after conflicts with concurrent writers.
+func (h *CatalogHandler) retryCommit(ctx context.Context, log logger.Logger, base *table.Table, reqs []table.Requirement, updates []table.Update) commit.Result {
+	metadata, metadataLoc, err := h.catalog.CommitTable(ctx, base, reqs, commit.GuardUnloaded(h.catalog.CatalogType(), base, updates))
+	attempts := 1
+
+	for h.commitRetry.Enabled && err != nil && isCommitConflict(err) && attempts < h.commitRetry.MaxAttempts {
+		if waitErr := h.commitRetry.Wait(ctx, attempts); waitErr != nil {
+			break
+		}
+
+		current, loadErr := h.catalog.LoadTable(ctx, base.Identifier(), nil)
+		if loadErr != nil {
+			break
+		}
+		// Always rebase the original request so that rebased snapshots don't
+		// pile up across attempts.
+		rebasedReqs, rebasedUpdates, rebaseErr := commit.Rebase(ctx, current, reqs, updates)
+		if rebaseErr != nil {
+			if !errors.Is(rebaseErr, commit.ErrNotRebaseable) {
+				log.Warnf("failed to rebase commit: %s", rebaseErr)
+			}
+			break
+		}
+
+		attempts++
+		metrics.RecordCommitRetry()
+		metadata, metadataLoc, err = h.catalog.CommitTable(ctx, current, rebasedReqs, rebasedUpdates)
+	}
+	return commit.Result{Metadata: metadata, Location: metadataLoc, Attempts: attempts, Batched: 1, Err: err}
+}
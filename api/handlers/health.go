@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/health"
+	"github.com/xixipi-lining/iceberg-rest-catalog/redact"
+)
+
+type HealthHandler struct {
+	checker  *health.Checker
+	redactor *redact.Redactor
+}
+
+func NewHealthHandler(checker *health.Checker, redactor *redact.Redactor) *HealthHandler {
+	if redactor == nil {
+		redactor = redact.Default()
+	}
+	return &HealthHandler{checker: checker, redactor: redactor}
+}
+
+// Liveness reports that the process is up and serving requests.
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusUp})
+}
+
+// Readiness runs the configured probes and answers 503 if any is down.
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	report := h.checker.Check(c.Request.Context())
+
+	// Probe errors come straight from the backend and FileIO clients.
+	for i := range report.Components {
+		report.Components[i].Error = h.redactor.String(report.Components[i].Error)
+	}
+
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
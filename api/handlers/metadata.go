@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/apache/iceberg-go/table"
+)
+
+// tableProperties returns props with sensitive values masked. The values are
+// not remembered as secrets: every table served would grow the set the
+// redactor scrubs logs with.
+func (h *CatalogHandler) tableProperties(props map[string]string) map[string]string {
+	return h.redactor.Properties(props)
+}
+
+// marshalMetadata returns the encoding of writeMetadata.
+func (h *CatalogHandler) marshalMetadata(md table.Metadata) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := h.writeMetadata(&buf, md); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeMetadata writes the JSON encoding of md to w with sensitive table
+// properties masked. Table properties are served twice, as config and as
+// metadata.properties, and credentials must be masked in both.
+//
+// The output is that of json.Marshal. Only the lists that grow with the
+// table's history are encoded one element at a time, so that the whole
+// encoding is never held in memory: a copy of md is marshaled with each of
+// them holding a single zero element, which is then replaced by the stream.
+func (h *CatalogHandler) writeMetadata(w io.Writer, md table.Metadata) error {
+	v := reflect.ValueOf(md)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("unsupported table metadata type %T", md)
+	}
+	masked := reflect.New(v.Elem().Type()).Elem()
+	masked.Set(v.Elem())
+
+	props := masked.FieldByName("Props")
+	if !props.CanSet() || props.Kind() != reflect.Map {
+		return fmt.Errorf("can't mask the properties of %T", md)
+	}
+	props.Set(reflect.ValueOf(h.tableProperties(md.Properties())).Convert(props.Type()))
+
+	var lists []streamedList
+	for _, name := range streamedFields {
+		l, ok, err := placeholder(masked, name)
+		if err != nil {
+			return err
+		}
+		if ok {
+			lists = append(lists, l)
+		}
+	}
+
+	data, err := json.Marshal(masked.Addr().Interface())
+	if err != nil {
+		return err
+	}
+	for i := range lists {
+		lists[i].offset = bytes.Index(data, lists[i].marker)
+		if lists[i].offset < 0 {
+			// The encoding doesn't hold the placeholder where expected, so
+			// the lists are encoded along with the rest.
+			for _, l := range lists {
+				l.field.Set(l.values)
+			}
+			data, err = json.Marshal(masked.Addr().Interface())
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		}
+	}
+	slices.SortFunc(lists, func(a, b streamedList) int { return a.offset - b.offset })
+
+	ew := &errWriter{w: w}
+	pos := 0
+	for _, l := range lists {
+		ew.write(data[pos:l.offset])
+		ew.write(l.key)
+		ew.write([]byte(":["))
+		for j := range l.values.Len() {
+			if j > 0 {
+				ew.write([]byte(","))
+			}
+			if err := ew.encode(l.values.Index(j).Addr().Interface()); err != nil {
+				return err
+			}
+		}
+		ew.write([]byte("]"))
+		pos = l.offset + len(l.marker)
+	}
+	ew.write(data[pos:])
+	return ew.err
+}
+
+// streamedFields are the fields of the metadata types holding the lists that
+// grow with the table's history.
+var streamedFields = []string{"SnapshotList", "SnapshotLog", "MetadataLog"}
+
+// streamedList is a list of metadata taken out of the encoding and written
+// one element at a time in place of marker.
+type streamedList struct {
+	field  reflect.Value
+	values reflect.Value
+	key    []byte
+	marker []byte
+	offset int
+}
+
+// placeholder replaces the list in the field name of md with a single zero
+// element, unless the field is missing or the list is empty.
+func placeholder(md reflect.Value, name string) (streamedList, bool, error) {
+	field := md.FieldByName(name)
+	if !field.CanSet() || field.Kind() != reflect.Slice || field.Len() == 0 {
+		return streamedList{}, false, nil
+	}
+	sf, _ := md.Type().FieldByName(name)
+	tag, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if tag == "" || tag == "-" {
+		return streamedList{}, false, nil
+	}
+
+	zero := reflect.MakeSlice(field.Type(), 1, 1)
+	elem, err := json.Marshal(zero.Index(0).Addr().Interface())
+	if err != nil {
+		return streamedList{}, false, err
+	}
+	key, err := json.Marshal(tag)
+	if err != nil {
+		return streamedList{}, false, err
+	}
+
+	l := streamedList{
+		field:  field,
+		values: reflect.New(field.Type()).Elem(),
+		key:    key,
+		marker: slices.Concat(key, []byte(":["), elem, []byte("]")),
+	}
+	l.values.Set(field)
+	field.Set(zero)
+	return l, true, nil
+}
+
+// errWriter writes and encodes until the first error, which it keeps.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) write(data []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(data)
+	}
+}
+
+func (e *errWriter) encode(v any) error {
+	if e.err != nil {
+		return e.err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.write(data)
+	return e.err
+}
+
+// MetadataEncoder writes the JSON encoding of table metadata, e.g. from a
+// cache of already encoded metadata files. encode produces the encoding when
+// it isn't at hand.
+type MetadataEncoder interface {
+	WriteMetadata(w io.Writer, identifier table.Identifier, location string, encode func(w io.Writer) error) error
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) WriteMetadata(w io.Writer, _ table.Identifier, _ string, encode func(w io.Writer) error) error {
+	return encode(w)
+}
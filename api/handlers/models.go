@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/apache/iceberg-go"
 	"github.com/apache/iceberg-go/table"
+	"github.com/xixipi-lining/iceberg-rest-catalog/audit"
 )
 
 const namespaceSeparator = "\x1F"
@@ -90,3 +92,15 @@ type RenameTableRequest struct {
 	Source      Identifier `json:"source"`
 	Destination Identifier `json:"destination"`
 }
+
+type ListAuditEventsRequest struct {
+	Namespace *string   `form:"namespace"`
+	Table     string    `form:"table"`
+	Since     time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until     time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit     *int      `form:"limit"`
+}
+
+type ListAuditEventsResponse struct {
+	Events []audit.Event `json:"events"`
+}
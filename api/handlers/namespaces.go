@@ -7,6 +7,8 @@ import (
 
 	"github.com/apache/iceberg-go/catalog"
 	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/apierror"
+	"github.com/xixipi-lining/iceberg-rest-catalog/audit"
 )
 
 func (h *CatalogHandler) ListNamespaces(c *gin.Context) {
@@ -14,9 +16,7 @@ func (h *CatalogHandler) ListNamespaces(c *gin.Context) {
 
 	var req ListNamespacesRequest
 	if err := c.BindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrBadRequest,
-		})
+		c.JSON(http.StatusBadRequest, apierror.NewResponse(c, apierror.ErrBadRequest))
 		return
 	}
 
@@ -28,15 +28,11 @@ func (h *CatalogHandler) ListNamespaces(c *gin.Context) {
 	namespaces, err := h.catalog.ListNamespaces(c.Request.Context(), parent)
 	if err != nil {
 		if errors.Is(err, catalog.ErrNoSuchNamespace) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrNamespaceNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrNamespaceNotFound))
 			return
 		}
 		log.Errorf("failed to list namespaces: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
 
@@ -50,24 +46,22 @@ func (h *CatalogHandler) CreateNamespace(c *gin.Context) {
 	log := getLogger(c)
 
 	var req CreateNamespaceRequest
-	if err := c.BindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrBadRequest,
-		})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.WriteBindError(c, err)
 		return
 	}
+
+	event := h.newAuditEvent(c, audit.OpCreateNamespace, req.Namespace, "")
+	defer h.recordAudit(c, event)
+
 	err := h.catalog.CreateNamespace(c.Request.Context(), req.Namespace, req.Properties)
 	if err != nil {
 		if errors.Is(err, catalog.ErrNamespaceAlreadyExists) {
-			c.JSON(http.StatusConflict, ErrorResponse{
-				Error: ErrNamespaceAlreadyExists,
-			})
+			c.JSON(http.StatusConflict, apierror.NewResponse(c, apierror.ErrNamespaceAlreadyExists))
 			return
 		}
 		log.Errorf("failed to create namespace: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
 	c.JSON(http.StatusOK, CreateNamespaceResponse(req))
@@ -80,15 +74,11 @@ func (h *CatalogHandler) LoadNamespaceMetadata(c *gin.Context) {
 	properties, err := h.catalog.LoadNamespaceProperties(c.Request.Context(), namespace)
 	if err != nil {
 		if errors.Is(err, catalog.ErrNoSuchNamespace) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrNamespaceNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrNamespaceNotFound))
 			return
 		}
 		log.Errorf("failed to load namespace metadata: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
 	c.JSON(http.StatusOK, LoadNamespaceMetadataResponse{
@@ -103,15 +93,11 @@ func (h *CatalogHandler) NamespaceExists(c *gin.Context) {
 	exists, err := h.catalog.CheckNamespaceExists(c.Request.Context(), namespace)
 	if err != nil {
 		log.Errorf("failed to check namespace exists: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
 	if !exists {
-		c.JSON(http.StatusNotFound, ErrorResponse{
-			Error: ErrNamespaceNotFound,
-		})
+		c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrNamespaceNotFound))
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -120,24 +106,22 @@ func (h *CatalogHandler) NamespaceExists(c *gin.Context) {
 func (h *CatalogHandler) DropNamespace(c *gin.Context) {
 	log := getLogger(c)
 	namespace := strings.Split(c.Param("namespace"), namespaceSeparator)
+
+	event := h.newAuditEvent(c, audit.OpDropNamespace, namespace, "")
+	defer h.recordAudit(c, event)
+
 	err := h.catalog.DropNamespace(c.Request.Context(), namespace)
 	if err != nil {
 		if errors.Is(err, catalog.ErrNoSuchNamespace) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrNamespaceNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrNamespaceNotFound))
 			return
 		}
 		if errors.Is(err, catalog.ErrNamespaceNotEmpty) {
-			c.JSON(http.StatusConflict, ErrorResponse{
-				Error: ErrNamespaceNotEmpty,
-			})
+			c.JSON(http.StatusConflict, apierror.NewResponse(c, apierror.ErrNamespaceNotEmpty))
 			return
 		}
 		log.Errorf("failed to drop namespace: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -149,34 +133,35 @@ func (h *CatalogHandler) UpdateProperties(c *gin.Context) {
 	namespace := strings.Split(c.Param("namespace"), namespaceSeparator)
 
 	var req UpdatePropertiesRequest
-	if err := c.BindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrBadRequest,
-		})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.WriteBindError(c, err)
 		return
 	}
 
 	for _, removal := range req.Removals {
 		if _, exists := req.Updates[removal]; exists {
-			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
-				Error: ErrUnprocessableEntityDuplicateKey,
-			})
+			c.JSON(http.StatusUnprocessableEntity, apierror.NewResponse(c, apierror.ErrUnprocessableEntityDuplicateKey))
 			return
 		}
 	}
 
+	event := h.newAuditEvent(c, audit.OpUpdateProperties, namespace, "")
+	if len(req.Updates) > 0 {
+		event.Updates = append(event.Updates, "set-properties")
+	}
+	if len(req.Removals) > 0 {
+		event.Updates = append(event.Updates, "remove-properties")
+	}
+	defer h.recordAudit(c, event)
+
 	summary, err := h.catalog.UpdateNamespaceProperties(c.Request.Context(), namespace, req.Removals, req.Updates)
 	if err != nil {
 		if errors.Is(err, catalog.ErrNoSuchNamespace) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrNamespaceNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrNamespaceNotFound))
 			return
 		}
 		log.Errorf("failed to update namespace properties: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
 	c.JSON(http.StatusOK, UpdatePropertiesResponse{
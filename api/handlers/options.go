@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"github.com/xixipi-lining/iceberg-rest-catalog/audit"
+	"github.com/xixipi-lining/iceberg-rest-catalog/commit"
+	"github.com/xixipi-lining/iceberg-rest-catalog/redact"
+)
+
+type Option func(*CatalogHandler)
+
+// WithAuditSink records every mutating call to the given sink.
+func WithAuditSink(sink audit.Sink) Option {
+	return func(h *CatalogHandler) {
+		h.auditSink = sink
+	}
+}
+
+// WithRedactor masks sensitive properties in responses. Without it only the
+// built-in sensitive keys are masked.
+func WithRedactor(redactor *redact.Redactor) Option {
+	return func(h *CatalogHandler) {
+		h.redactor = redactor
+	}
+}
+
+// WithMetadataEncoder replaces encoding the metadata of every loaded table.
+func WithMetadataEncoder(encoder MetadataEncoder) Option {
+	return func(h *CatalogHandler) {
+		if encoder != nil {
+			h.encoder = encoder
+		}
+	}
+}
+
+// WithCommitRetry retries commits that conflict with concurrent writers on
+// the server when their updates can be safely reapplied.
+func WithCommitRetry(cfg commit.Config) Option {
+	return func(h *CatalogHandler) {
+		h.commitRetry = cfg
+	}
+}
+
+// WithCommitQueue serializes the commits to each table, and optionally
+// batches them, instead of letting them race in the backend.
+func WithCommitQueue(cfg commit.QueueConfig) Option {
+	return func(h *CatalogHandler) {
+		if cfg.Enabled {
+			h.commitQueue = commit.NewQueue(cfg, h.catalog)
+		}
+	}
+}
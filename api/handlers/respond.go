@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/apierror"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/phases"
+)
+
+// writeJSON is c.JSON with marshalling and writing timed separately.
+func writeJSON(c *gin.Context, status int, v any) {
+	done := phases.Start(c, phases.Marshal)
+	body, err := json.Marshal(v)
+	done()
+	if err != nil {
+		getLogger(c).Errorf("failed to marshal response: %s", err)
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
+		return
+	}
+
+	done = phases.Start(c, phases.Write)
+	c.Data(status, "application/json; charset=utf-8", body)
+	done()
+}
+
+// timedWriter adds up the time spent in Write, to tell writing apart from
+// encoding in a streamed response.
+type timedWriter struct {
+	w       io.Writer
+	elapsed time.Duration
+}
+
+func (t *timedWriter) Write(data []byte) (int, error) {
+	start := time.Now()
+	n, err := t.w.Write(data)
+	t.elapsed += time.Since(start)
+	return n, err
+}
+
+// streamJSON writes a response body that encode produces piece by piece
+// straight to the client, without holding the whole body in memory. Time
+// spent in writes counts as the write phase and the rest as marshalling.
+//
+// The status is sent with the first bytes, so encode must not fail for
+// reasons it could have checked up front. If it fails midway, the error is
+// logged and the body is left truncated, which clients reject as invalid JSON.
+func streamJSON(c *gin.Context, status int, encode func(w io.Writer) error) {
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(status)
+
+	w := &timedWriter{w: c.Writer}
+	start := time.Now()
+	err := encode(w)
+	phases.Add(c, phases.Write, w.elapsed)
+	phases.Add(c, phases.Marshal, time.Since(start)-w.elapsed)
+	if err != nil {
+		_ = c.Error(err)
+		if c.Request.Context().Err() == nil {
+			getLogger(c).Errorf("failed to stream response: %s", err)
+		}
+	}
+}
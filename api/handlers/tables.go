@@ -3,12 +3,22 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/catalog/rest"
 	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/apierror"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/phases"
+	"github.com/xixipi-lining/iceberg-rest-catalog/audit"
+	"github.com/xixipi-lining/iceberg-rest-catalog/commit"
 	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+	"github.com/xixipi-lining/iceberg-rest-catalog/metrics"
+	"github.com/xixipi-lining/iceberg-rest-catalog/redact"
 )
 
 type Config struct {
@@ -17,22 +27,47 @@ type Config struct {
 }
 
 type CatalogHandler struct {
-	config  Config
-	catalog catalog.Catalog
+	// config is replaced as a whole when the config file is reloaded.
+	config    atomic.Pointer[Config]
+	catalog   catalog.Catalog
+	auditSink audit.Sink
+	redactor  *redact.Redactor
+	encoder   MetadataEncoder
+
+	commitRetry commit.Config
+	commitQueue *commit.Queue
 }
 
+// fallbackLogger serves requests that didn't pass the logger middleware. It
+// is created once, since creating a logger changes zerolog's global settings
+// and would race with concurrent requests.
+var fallbackLogger = sync.OnceValue(func() logger.Logger {
+	return logger.NewLogger(&logger.Config{
+		Debug: true,
+	})
+})
+
 func getLogger(c *gin.Context) logger.Logger {
 	log, ok := c.Get("logger")
 	if !ok {
-		return logger.NewLogger(&logger.Config{
-			Debug: true,
-		})
+		return fallbackLogger()
 	}
 	return log.(logger.Logger)
 }
 
-func NewCatalogHandler(catalog catalog.Catalog, config Config) *CatalogHandler {
-	return &CatalogHandler{catalog: catalog, config: config}
+func NewCatalogHandler(catalog catalog.Catalog, config Config, opts ...Option) *CatalogHandler {
+	h := &CatalogHandler{catalog: catalog, redactor: redact.Default(), encoder: jsonEncoder{}}
+	h.config.Store(&config)
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// SetConfig replaces the defaults and overrides served by GetConfig. Requests
+// in flight keep the config they started with.
+func (h *CatalogHandler) SetConfig(config Config) {
+	h.config.Store(&config)
 }
 
 func (h *CatalogHandler) GetConfig(c *gin.Context) {
@@ -42,7 +77,11 @@ func (h *CatalogHandler) GetConfig(c *gin.Context) {
 		log.Warn("warehouse query parameter is not supported")
 	}
 
-	c.JSON(http.StatusOK, h.config)
+	config := h.config.Load()
+	c.JSON(http.StatusOK, Config{
+		Defaults:  h.redactor.Properties(config.Defaults),
+		Overrides: h.redactor.Properties(config.Overrides),
+	})
 }
 
 func (h *CatalogHandler) ListTables(c *gin.Context) {
@@ -50,13 +89,17 @@ func (h *CatalogHandler) ListTables(c *gin.Context) {
 
 	namespace := strings.Split(c.Param("namespace"), namespaceSeparator)
 
-	var resTables []Identifier
+	// Identifiers are written as the backend yields them. Until the first one
+	// arrives a backend error can still be answered with a 500.
+	var w io.Writer
 	for table, err := range h.catalog.ListTables(c.Request.Context(), namespace) {
 		if err != nil {
 			log.Errorf("failed to list tables: %s", err)
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Error: ErrInternalServerError,
-			})
+			if w == nil {
+				c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
+			} else {
+				_ = c.Error(err)
+			}
 			return
 		}
 		// Add boundary check to prevent panic
@@ -64,15 +107,36 @@ func (h *CatalogHandler) ListTables(c *gin.Context) {
 			log.Warn("received empty table identifier")
 			continue
 		}
-		resTables = append(resTables, Identifier{
+
+		ident, err := json.Marshal(Identifier{
 			Namespace: table[:len(table)-1],
 			Name:      table[len(table)-1],
 		})
+		if err != nil {
+			log.Errorf("failed to marshal table identifier: %s", err)
+			return
+		}
+
+		if w == nil {
+			c.Header("Content-Type", "application/json; charset=utf-8")
+			c.Status(http.StatusOK)
+			w = c.Writer
+			if _, err := io.WriteString(w, `{"identifiers":[`); err != nil {
+				return
+			}
+		} else if _, err := io.WriteString(w, ","); err != nil {
+			return
+		}
+		if _, err := w.Write(ident); err != nil {
+			return
+		}
 	}
 
-	c.JSON(http.StatusOK, ListTablesResponse{
-		Identifiers: resTables,
-	})
+	if w == nil {
+		c.JSON(http.StatusOK, ListTablesResponse{})
+		return
+	}
+	_, _ = io.WriteString(w, "]}")
 }
 
 func (h *CatalogHandler) CreateTable(c *gin.Context) {
@@ -82,16 +146,15 @@ func (h *CatalogHandler) CreateTable(c *gin.Context) {
 
 	var req CreateTableRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrBadRequest,
-		})
+		apierror.WriteBindError(c, err)
 		return
 	}
 
+	event := h.newAuditEvent(c, audit.OpCreateTable, namespace, req.Name)
+	defer h.recordAudit(c, event)
+
 	if req.StageCreate {
-		c.JSON(http.StatusNotImplemented, ErrorResponse{
-			Error: ErrNotImplemented,
-		})
+		c.JSON(http.StatusNotImplemented, apierror.NewResponse(c, apierror.ErrNotImplemented))
 		return
 	}
 
@@ -109,42 +172,40 @@ func (h *CatalogHandler) CreateTable(c *gin.Context) {
 		opts = append(opts, catalog.WithProperties(req.Props))
 	}
 
+	done := phases.Start(c, phases.Backend)
 	table, err := h.catalog.CreateTable(c.Request.Context(), append(namespace, req.Name), req.Schema, opts...)
+	done()
 	if err != nil {
 		if errors.Is(err, catalog.ErrNoSuchNamespace) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrNamespaceNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrNamespaceNotFound))
 			return
 		}
 		if errors.Is(err, catalog.ErrTableAlreadyExists) {
-			c.JSON(http.StatusConflict, ErrorResponse{
-				Error: ErrTableAlreadyExists,
-			})
+			c.JSON(http.StatusConflict, apierror.NewResponse(c, apierror.ErrTableAlreadyExists))
 			return
 		}
 		log.Errorf("failed to create table: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
 
-	metadata, err := json.Marshal(table.Metadata())
+	done = phases.Start(c, phases.Marshal)
+	metadata, err := h.marshalMetadata(table.Metadata())
+	done()
 	if err != nil {
 		log.Errorf("failed to marshal metadata: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
+	event.NewMetadataLocation = table.MetadataLocation()
+
 	resp := LoadTableResponse{
 		MetadataLoc: table.MetadataLocation(),
 		Metadata:    metadata,
-		Config:      table.Properties(),
+		Config:      h.tableProperties(table.Properties()),
 	}
 
-	c.JSON(http.StatusOK, resp)
+	writeJSON(c, http.StatusOK, resp)
 }
 
 func (h *CatalogHandler) UpdateTable(c *gin.Context) {
@@ -154,68 +215,86 @@ func (h *CatalogHandler) UpdateTable(c *gin.Context) {
 
 	tableName := c.Param("table")
 	if tableName == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrBadRequest,
-		})
+		c.JSON(http.StatusBadRequest, apierror.NewResponse(c, apierror.ErrBadRequest))
 		return
 	}
 
 	var req UpdateTableRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrBadRequest,
-		})
+		apierror.WriteBindError(c, err)
 		return
 	}
 
-	table, err := h.catalog.LoadTable(c.Request.Context(), append(namespace, tableName), nil)
-	if err != nil {
-		if errors.Is(err, catalog.ErrNoSuchNamespace) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrNamespaceNotFound,
-			})
-			return
-		}
-		if errors.Is(err, catalog.ErrNoSuchTable) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrTableNotFound,
-			})
+	event := h.newAuditEvent(c, audit.OpUpdateTable, namespace, tableName)
+	event.Updates = summarizeUpdates(req.Updates)
+	defer h.recordAudit(c, event)
+
+	// The table is only the base handed to CommitTable. Backends that reload
+	// the table on commit only use its identifier, so it is loaded for the
+	// others alone.
+	identifier := append(namespace, tableName)
+	table := commit.Unloaded(identifier, h.catalog)
+	if !commit.ReloadsOnCommit(h.catalog.CatalogType()) {
+		var err error
+		done := phases.Start(c, phases.Backend)
+		table, err = h.catalog.LoadTable(c.Request.Context(), identifier, nil)
+		done()
+		if err != nil {
+			if errors.Is(err, catalog.ErrNoSuchNamespace) {
+				c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrNamespaceNotFound))
+				return
+			}
+			if errors.Is(err, catalog.ErrNoSuchTable) {
+				c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrTableNotFound))
+				return
+			}
+			log.Errorf("failed to load table: %s", err)
+			c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 			return
 		}
-		log.Errorf("failed to load table: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
-		return
 	}
 
-	metadata, metadataLoc, err := h.catalog.CommitTable(c.Request.Context(), table, req.Requirements, req.Updates)
+	event.OldMetadataLocation = table.MetadataLocation()
+
+	metadata, metadataLoc, err := h.commitTable(c, table, req.Requirements, req.Updates)
 	if err != nil {
+		if errors.Is(err, commit.ErrQueueFull) || errors.Is(err, commit.ErrQueueTimeout) {
+			c.JSON(http.StatusServiceUnavailable, apierror.NewResponse(c, apierror.ErrCommitQueueBusy))
+			return
+		}
+		if isCommitConflict(err) {
+			metrics.RecordCommit(metrics.CommitConflict)
+			c.JSON(http.StatusConflict, apierror.NewResponse(c, apierror.ErrCommitFailed))
+			return
+		}
+		metrics.RecordCommit(metrics.CommitError)
 		if errors.Is(err, catalog.ErrNoSuchNamespace) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrNamespaceNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrNamespaceNotFound))
 			return
 		}
 		if errors.Is(err, catalog.ErrNoSuchTable) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrTableNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrTableNotFound))
 			return
 		}
 		log.Errorf("failed to commit table: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
 
-	metadataBytes, err := json.Marshal(metadata)
+	metrics.RecordCommit(metrics.CommitSuccess)
+	if previous := previousMetadataLocation(metadata); previous != "" && metadataLoc != event.OldMetadataLocation {
+		// The base may be a cached copy, the metadata log has the location
+		// the commit actually replaced.
+		event.OldMetadataLocation = previous
+	}
+	event.NewMetadataLocation = metadataLoc
+
+	done := phases.Start(c, phases.Marshal)
+	metadataBytes, err := h.marshalMetadata(metadata)
+	done()
 	if err != nil {
 		log.Errorf("failed to marshal metadata: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
 	resp := UpdateTableResponse{
@@ -223,7 +302,7 @@ func (h *CatalogHandler) UpdateTable(c *gin.Context) {
 		Metadata:    metadataBytes,
 	}
 
-	c.JSON(http.StatusOK, resp)
+	writeJSON(c, http.StatusOK, resp)
 }
 
 func (h *CatalogHandler) LoadTable(c *gin.Context) {
@@ -233,43 +312,56 @@ func (h *CatalogHandler) LoadTable(c *gin.Context) {
 
 	tableName := c.Param("table")
 
+	done := phases.Start(c, phases.Backend)
 	table, err := h.catalog.LoadTable(c.Request.Context(), append(namespace, tableName), nil)
+	done()
 	if err != nil {
 		if errors.Is(err, catalog.ErrNoSuchNamespace) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrNamespaceNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrNamespaceNotFound))
 			return
 		}
 		if errors.Is(err, catalog.ErrNoSuchTable) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrTableNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrTableNotFound))
 			return
 		}
 		log.Errorf("failed to load table: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
 
-	metadata, err := json.Marshal(table.Metadata())
+	// The small fields are encoded up front so that only the metadata, which
+	// can be megabytes, is encoded while streaming.
+	location, err := json.Marshal(table.MetadataLocation())
 	if err != nil {
-		log.Errorf("failed to marshal metadata: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		log.Errorf("failed to marshal metadata location: %s", err)
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
-
-	resp := LoadTableResponse{
-		MetadataLoc: table.MetadataLocation(),
-		Metadata:    metadata,
-		Config:      table.Properties(),
+	config, err := json.Marshal(h.tableProperties(table.Properties()))
+	if err != nil {
+		log.Errorf("failed to marshal table config: %s", err)
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
+		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	// Written field by field in the layout of LoadTableResponse.
+	streamJSON(c, http.StatusOK, func(w io.Writer) error {
+		for _, part := range [][]byte{[]byte(`{"metadata-location":`), location, []byte(`,"metadata":`)} {
+			if _, err := w.Write(part); err != nil {
+				return err
+			}
+		}
+		encode := func(w io.Writer) error { return h.writeMetadata(w, table.Metadata()) }
+		if err := h.encoder.WriteMetadata(w, table.Identifier(), table.MetadataLocation(), encode); err != nil {
+			return err
+		}
+		for _, part := range [][]byte{[]byte(`,"config":`), config, []byte(`}`)} {
+			if _, err := w.Write(part); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (h *CatalogHandler) DropTable(c *gin.Context) {
@@ -279,39 +371,42 @@ func (h *CatalogHandler) DropTable(c *gin.Context) {
 
 	tableName := c.Param("table")
 	if tableName == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrBadRequest,
-		})
+		c.JSON(http.StatusBadRequest, apierror.NewResponse(c, apierror.ErrBadRequest))
 		return
 	}
 
 	purgeRequested := c.Query("purgeRequested")
 	if purgeRequested == "true" {
 		log.Warn("purgeRequested query parameter is not supported")
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrNotImplemented,
-		})
+		c.JSON(http.StatusBadRequest, apierror.NewResponse(c, apierror.ErrNotImplemented))
 		return
 	}
 
+	event := h.newAuditEvent(c, audit.OpDropTable, namespace, tableName)
+	defer h.recordAudit(c, event)
+
+	if h.auditSink != nil {
+		// DropTable doesn't return the metadata the table pointed at, which is
+		// what it takes to register the table again after an accidental drop.
+		// Only paid with auditing on, and drops are rare. Best effort:
+		// failures surface from DropTable below.
+		if table, err := h.catalog.LoadTable(c.Request.Context(), append(namespace, tableName), nil); err == nil {
+			event.OldMetadataLocation = table.MetadataLocation()
+		}
+	}
+
 	err := h.catalog.DropTable(c.Request.Context(), append(namespace, tableName))
 	if err != nil {
 		if errors.Is(err, catalog.ErrNoSuchNamespace) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrNamespaceNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrNamespaceNotFound))
 			return
 		}
 		if errors.Is(err, catalog.ErrNoSuchTable) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrTableNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrTableNotFound))
 			return
 		}
 		log.Errorf("failed to drop table: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
 
@@ -325,37 +420,27 @@ func (h *CatalogHandler) TableExists(c *gin.Context) {
 
 	tableName := c.Param("table")
 	if tableName == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrBadRequest,
-		})
+		c.JSON(http.StatusBadRequest, apierror.NewResponse(c, apierror.ErrBadRequest))
 		return
 	}
 
 	exists, err := h.catalog.CheckTableExists(c.Request.Context(), append(namespace, tableName))
 	if err != nil {
 		if errors.Is(err, catalog.ErrNoSuchNamespace) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrNamespaceNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrNamespaceNotFound))
 			return
 		}
 		if errors.Is(err, catalog.ErrNoSuchTable) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrTableNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrTableNotFound))
 			return
 		}
 		log.Errorf("failed to check table exists: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
 
 	if !exists {
-		c.JSON(http.StatusNotFound, ErrorResponse{
-			Error: ErrTableNotFound,
-		})
+		c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrTableNotFound))
 		return
 	}
 
@@ -367,36 +452,50 @@ func (h *CatalogHandler) RenameTable(c *gin.Context) {
 
 	var req RenameTableRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrBadRequest,
-		})
+		apierror.WriteBindError(c, err)
 		return
 	}
 
-	_, err := h.catalog.RenameTable(
+	event := h.newAuditEvent(c, audit.OpRenameTable, req.Source.Namespace, req.Source.Name)
+	event.ToNamespace = req.Destination.Namespace
+	event.ToTable = req.Destination.Name
+	defer h.recordAudit(c, event)
+
+	table, err := h.catalog.RenameTable(
 		c.Request.Context(),
 		append(req.Source.Namespace, req.Source.Name),
 		append(req.Destination.Namespace, req.Destination.Name),
 	)
 	if err != nil {
 		if errors.Is(err, catalog.ErrNoSuchNamespace) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrNamespaceNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrNamespaceNotFound))
 			return
 		}
 		if errors.Is(err, catalog.ErrNoSuchTable) {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: ErrTableNotFound,
-			})
+			c.JSON(http.StatusNotFound, apierror.NewResponse(c, apierror.ErrTableNotFound))
 			return
 		}
 		log.Errorf("failed to rename table: %s", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrInternalServerError,
-		})
+		c.JSON(http.StatusInternalServerError, apierror.NewResponse(c, apierror.ErrInternalServerError))
 		return
 	}
 
+	// A rename moves the table pointer without writing new metadata.
+	event.OldMetadataLocation = table.MetadataLocation()
+
 	c.Status(http.StatusOK)
 }
+
+// isCommitConflict reports whether a commit failed because the table changed
+// underneath it, either failing a requirement or losing the race to swap the
+// metadata pointer. Backends only expose this through their error messages.
+func isCommitConflict(err error) bool {
+	if errors.Is(err, rest.ErrCommitFailed) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "requirement failed") ||
+		strings.Contains(msg, "UUID mismatch") ||
+		strings.Contains(msg, "has been updated by another process") ||
+		strings.Contains(msg, "ConcurrentModificationException")
+}
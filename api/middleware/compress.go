@@ -0,0 +1,291 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/apierror"
+	"github.com/xixipi-lining/iceberg-rest-catalog/validate"
+)
+
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+type CompressionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MinSize is the smallest response body, in bytes, that gets compressed.
+	MinSize int `yaml:"min-size"`
+	// Encodings lists the response encodings offered, most preferred first.
+	// Empty offers zstd then gzip.
+	Encodings []string `yaml:"encodings"`
+	// MaxRequestSize bounds the decompressed size of a compressed request
+	// body, in bytes. Zero means no limit.
+	MaxRequestSize int64 `yaml:"max-request-size"`
+}
+
+func (cfg CompressionConfig) Validate() error {
+	var r validate.Report
+	for i, enc := range cfg.Encodings {
+		if enc != EncodingGzip && enc != EncodingZstd {
+			r.Add(validate.Index("encodings", i), "unsupported encoding %q", enc)
+		}
+	}
+	if cfg.MinSize < 0 {
+		r.Add("min-size", "must not be negative")
+	}
+	return r.Err()
+}
+
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// maxDecoderWindow bounds the memory a zstd request body may make the decoder
+// allocate, which the frame header chooses otherwise. 8 MiB is the largest
+// window zstd uses short of --long.
+const maxDecoderWindow = 8 << 20
+
+var (
+	gzipWriters = sync.Pool{New: func() any {
+		return gzip.NewWriter(io.Discard)
+	}}
+	zstdWriters = sync.Pool{New: func() any {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return w
+	}}
+)
+
+// Compression compresses responses of at least cfg.MinSize bytes with the
+// client's preferred encoding from Accept-Encoding, and decompresses gzip or
+// zstd request bodies. Responses to HEAD requests and responses that already
+// set Content-Encoding, such as /metrics, are left alone.
+func Compression(cfg CompressionConfig) gin.HandlerFunc {
+	encodings := cfg.Encodings
+	if len(encodings) == 0 {
+		encodings = []string{EncodingZstd, EncodingGzip}
+	}
+
+	return func(c *gin.Context) {
+		decoder, err := decompressRequest(c, cfg.MaxRequestSize)
+		if err != nil {
+			if errors.Is(err, errUnsupportedEncoding) {
+				c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, apierror.NewResponse(c, apierror.ErrUnsupportedEncoding))
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, apierror.NewResponse(c, apierror.ErrBadRequest))
+			return
+		}
+		if decoder != nil {
+			// The server only closes the body it passed in, and a zstd
+			// decoder holds goroutines and buffers until it is closed.
+			defer decoder.Close()
+		}
+
+		if c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		c.Header("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"), encodings)
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, minSize: cfg.MinSize}
+		c.Writer = w
+		defer func() {
+			w.close()
+			// Outer middlewares see the bytes actually sent.
+			c.Writer = w.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
+
+// decompressRequest replaces a compressed request body with its decoded
+// content. It returns the decoder, which the caller closes once the request
+// is done, or nil if the body is left as is.
+func decompressRequest(c *gin.Context, maxSize int64) (io.Closer, error) {
+	encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+	if encoding == "" || encoding == "identity" || c.Request.Body == nil {
+		return nil, nil
+	}
+
+	var decoder io.ReadCloser
+	switch encoding {
+	case EncodingGzip:
+		r, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			return nil, err
+		}
+		decoder = r
+	case EncodingZstd:
+		r, err := zstd.NewReader(c.Request.Body,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxWindow(maxDecoderWindow),
+			zstd.WithDecoderMaxMemory(maxDecoderWindow))
+		if err != nil {
+			return nil, err
+		}
+		decoder = r.IOReadCloser()
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnsupportedEncoding, encoding)
+	}
+
+	body := decoder
+	if maxSize > 0 {
+		body = http.MaxBytesReader(c.Writer, body, maxSize)
+	}
+	c.Request.Body = body
+	c.Request.ContentLength = -1
+	c.Request.Header.Del("Content-Encoding")
+	c.Request.Header.Del("Content-Length")
+	return decoder, nil
+}
+
+// negotiateEncoding picks the first of offered that acceptEncoding allows,
+// honoring q-values and "*". It returns "" when the response should be sent
+// as is.
+func negotiateEncoding(acceptEncoding string, offered []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range offered {
+		q, ok := accepted[enc]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// compressWriter holds back the first minSize bytes of the response to
+// decide whether it is worth compressing.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minSize  int
+
+	buf     []byte
+	decided bool
+	encoder io.WriteCloser
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.minSize {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide starts compressing if the held back bytes reach minSize and the
+// handler didn't encode the response itself, then writes them out.
+func (w *compressWriter) decide() error {
+	w.decided = true
+	header := w.Header()
+	if len(w.buf) > 0 && len(w.buf) >= w.minSize && header.Get("Content-Encoding") == "" && bodyAllowed(w.Status()) {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		w.encoder = w.newEncoder()
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.encoder != nil {
+		_, err := w.encoder.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressWriter) newEncoder() io.WriteCloser {
+	switch w.encoding {
+	case EncodingGzip:
+		gw := gzipWriters.Get().(*gzip.Writer)
+		gw.Reset(w.ResponseWriter)
+		return gw
+	default:
+		zw := zstdWriters.Get().(*zstd.Encoder)
+		zw.Reset(w.ResponseWriter)
+		return zw
+	}
+}
+
+func (w *compressWriter) close() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	if w.encoder == nil {
+		return
+	}
+	_ = w.encoder.Close()
+	switch enc := w.encoder.(type) {
+	case *gzip.Writer:
+		gzipWriters.Put(enc)
+	case *zstd.Encoder:
+		zstdWriters.Put(enc)
+	}
+	w.encoder = nil
+}
+
+func bodyAllowed(status int) bool {
+	return !slices.Contains([]int{http.StatusNoContent, http.StatusNotModified}, status) && status >= http.StatusOK
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+type CORSConfig struct {
+	// Enabled turns CORS handling on. When off, browsers on other origins
+	// cannot call the catalog at all.
+	Enabled bool `yaml:"enabled"`
+	// AllowOrigins lists the permitted origins, optionally with a "*"
+	// wildcard such as "https://*.example.com". Empty or ["*"] allows all.
+	AllowOrigins     []string      `yaml:"allow-origins"`
+	AllowMethods     []string      `yaml:"allow-methods"`
+	AllowHeaders     []string      `yaml:"allow-headers"`
+	ExposeHeaders    []string      `yaml:"expose-headers"`
+	AllowCredentials bool          `yaml:"allow-credentials"`
+	MaxAge           time.Duration `yaml:"max-age"`
+}
+
+// CORS builds the CORS middleware, starting from the gin-contrib defaults and
+// overriding whatever the config sets.
+func CORS(cfg CORSConfig) (gin.HandlerFunc, error) {
+	corsCfg := cors.DefaultConfig()
+
+	if len(cfg.AllowOrigins) == 0 || slices.Equal(cfg.AllowOrigins, []string{"*"}) {
+		if cfg.AllowCredentials {
+			return nil, errors.New("cors: allow-credentials requires explicit allow-origins")
+		}
+		corsCfg.AllowAllOrigins = true
+	} else {
+		corsCfg.AllowOrigins = cfg.AllowOrigins
+		corsCfg.AllowWildcard = slices.ContainsFunc(cfg.AllowOrigins, func(origin string) bool {
+			return strings.Contains(origin, "*")
+		})
+	}
+	if len(cfg.AllowMethods) > 0 {
+		corsCfg.AllowMethods = cfg.AllowMethods
+	}
+	if len(cfg.AllowHeaders) > 0 {
+		corsCfg.AllowHeaders = cfg.AllowHeaders
+	}
+	if cfg.MaxAge > 0 {
+		corsCfg.MaxAge = cfg.MaxAge
+	}
+	corsCfg.ExposeHeaders = cfg.ExposeHeaders
+	corsCfg.AllowCredentials = cfg.AllowCredentials
+
+	if err := corsCfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cors.New(corsCfg), nil
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/apierror"
+)
+
+// BodyLimit answers 413 to requests whose Content-Length is over maxBytes.
+// Other bodies are cut off at maxBytes as the handlers read them, and the
+// handlers answer 413 then; nothing is read here, so a client can't make the
+// server buffer a body before it is authenticated.
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > maxBytes {
+			// The body is not read, so the connection can't be reused.
+			c.Header("Connection", "close")
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, apierror.NewResponse(c, apierror.ErrRequestTooLarge))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
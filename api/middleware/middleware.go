@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -8,14 +9,28 @@ import (
 	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
 )
 
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client supplied request IDs, which end up in every
+// log line of the request.
+const maxRequestIDLength = 128
+
 func Logger(log logger.Logger) gin.HandlerFunc {
+	return LoggerWithAccessLog(log, log)
+}
+
+// LoggerWithAccessLog writes the per-request summary line to access while
+// handlers log through log.
+func LoggerWithAccessLog(log, access logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
 		method := c.Request.Method
 		clientIP := c.ClientIP()
 
-		requestID := uuid.New().String()
+		requestID := resolveRequestID(c)
+		c.Set("requestID", requestID)
+		c.Header(RequestIDHeader, requestID)
 		c.Set("logger", log.WithField("requestID", requestID).WithField("path", path).WithField("method", method).WithField("clientIP", clientIP))
 		c.Next()
 
@@ -23,7 +38,7 @@ func Logger(log logger.Logger) gin.HandlerFunc {
 		statusCode := c.Writer.Status()
 		size := c.Writer.Size()
 
-		log.
+		access.
 			WithField("requestID", requestID).
 			WithField("path", path).
 			WithField("method", method).
@@ -34,3 +49,47 @@ func Logger(log logger.Logger) gin.HandlerFunc {
 			Info("request")
 	}
 }
+
+// resolveRequestID prefers the client's X-Request-ID, then the trace ID of a
+// W3C traceparent header, and generates a fresh ID otherwise.
+func resolveRequestID(c *gin.Context) string {
+	if id := c.GetHeader(RequestIDHeader); isValidRequestID(id) {
+		return id
+	}
+
+	// traceparent is "version-traceid-parentid-flags".
+	parts := strings.Split(c.GetHeader("traceparent"), "-")
+	if len(parts) == 4 && len(parts[1]) == 32 && isValidRequestID(parts[1]) &&
+		parts[1] != strings.Repeat("0", 32) {
+		return parts[1]
+	}
+
+	return uuid.New().String()
+}
+
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Principal resolves the caller identity from the given request header, which
+// is expected to be set by an authenticating proxy in front of the server.
+func Principal(header string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := "anonymous"
+		if header != "" {
+			if value := c.GetHeader(header); value != "" {
+				principal = value
+			}
+		}
+		c.Set("principal", principal)
+		c.Next()
+	}
+}
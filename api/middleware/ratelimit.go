@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/apierror"
+	"github.com/xixipi-lining/iceberg-rest-catalog/validate"
+	"golang.org/x/time/rate"
+)
+
+const (
+	RateLimitByPrincipal = "principal"
+	RateLimitByClientIP  = "client-ip"
+)
+
+// limiterIdleTTL is how long an unused bucket is kept before it is evicted.
+const limiterIdleTTL = 10 * time.Minute
+
+type RateLimitConfig struct {
+	Rules []RateLimitRule `yaml:"rules"`
+}
+
+// RateLimitRule is a token bucket per distinct key value. All routes listed
+// in Routes form one group and share the bucket; an empty list applies the
+// rule to every route.
+type RateLimitRule struct {
+	// Key is either "principal" or "client-ip".
+	Key string `yaml:"key"`
+	// Routes are gin route templates, optionally prefixed by a method, e.g.
+	// "GET /v1/namespaces/:namespace/tables/:table".
+	Routes []string `yaml:"routes"`
+	// Rate is the number of requests per second refilled into the bucket.
+	Rate float64 `yaml:"rate"`
+	// Burst is the bucket size.
+	Burst int `yaml:"burst"`
+}
+
+func (r RateLimitRule) matches(method, route string) bool {
+	if len(r.Routes) == 0 {
+		return true
+	}
+	for _, entry := range r.Routes {
+		m, path, found := strings.Cut(entry, " ")
+		if !found {
+			path, m = entry, ""
+		}
+		if path == route && (m == "" || strings.EqualFold(m, method)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RateLimitRule) keyOf(c *gin.Context) string {
+	if r.Key == RateLimitByPrincipal {
+		return c.GetString("principal")
+	}
+	return c.ClientIP()
+}
+
+// Validate reports rules that would silently misbehave: an unknown key would
+// fall back to the client IP, and a zero rate or burst rejects every request.
+func (cfg RateLimitConfig) Validate() error {
+	var r validate.Report
+	for i, rule := range cfg.Rules {
+		path := validate.Index("rules", i)
+		if rule.Key != RateLimitByPrincipal && rule.Key != RateLimitByClientIP {
+			r.Add(validate.Join(path, "key"), "unknown rate limit key %q, expected principal or client-ip", rule.Key)
+		}
+		if rule.Rate <= 0 {
+			r.Add(validate.Join(path, "rate"), "must be positive")
+		}
+		if rule.Burst < 1 {
+			r.Add(validate.Join(path, "burst"), "must be at least 1")
+		}
+	}
+	return r.Err()
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type rateLimiter struct {
+	rules []RateLimitRule
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// RateLimit rejects requests exceeding any matching rule with 429 and a
+// Retry-After header. It must run after Principal so principal keyed rules
+// see the caller identity. cfg must have passed Validate.
+func RateLimit(cfg RateLimitConfig) gin.HandlerFunc {
+	rl := &rateLimiter{
+		rules:     cfg.Rules,
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		method := c.Request.Method
+
+		var keys []string
+		var rules []RateLimitRule
+		for i, rule := range rl.rules {
+			if rule.matches(method, route) {
+				keys = append(keys, strconv.Itoa(i)+"\x1F"+rule.keyOf(c))
+				rules = append(rules, rule)
+			}
+		}
+
+		if delay, ok := rl.reserve(keys, rules, time.Now()); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, apierror.NewResponse(c, apierror.ErrTooManyRequests))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// reserve takes a token from the bucket of every key, or from none of them:
+// a request rejected by one rule doesn't count against the others. When a
+// bucket is empty it returns how long the caller should wait before retrying.
+func (rl *rateLimiter) reserve(keys []string, rules []RateLimitRule, now time.Time) (time.Duration, bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.sweep(now)
+
+	taken := make([]*rate.Reservation, 0, len(keys))
+	for i, key := range keys {
+		b, ok := rl.buckets[key]
+		if !ok {
+			b = &bucket{limiter: rate.NewLimiter(rate.Limit(rules[i].Rate), rules[i].Burst)}
+			rl.buckets[key] = b
+		}
+		b.lastSeen = now
+
+		r := b.limiter.ReserveN(now, 1)
+		delay := time.Second
+		if r.OK() {
+			if delay = r.DelayFrom(now); delay <= 0 {
+				taken = append(taken, r)
+				continue
+			}
+			r.CancelAt(now)
+		}
+		for _, t := range taken {
+			t.CancelAt(now)
+		}
+		return delay, false
+	}
+	return 0, true
+}
+
+// sweep drops idle buckets so that keys such as client IPs don't grow the map
+// without bound.
+func (rl *rateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < limiterIdleTTL {
+		return
+	}
+	for key, b := range rl.buckets {
+		if now.Sub(b.lastSeen) > limiterIdleTTL {
+			delete(rl.buckets, key)
+		}
+	}
+	rl.lastSweep = now
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/apierror"
+)
+
+const namespaceSeparator = "\x1F"
+
+type ReadOnlyConfig struct {
+	// Enabled rejects writes to every catalog and namespace.
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Catalogs and Namespaces restrict read-only mode to the listed catalogs
+	// and to namespaces given in dotted form, including their children.
+	Catalogs   []string `json:"catalogs,omitempty" yaml:"catalogs"`
+	Namespaces []string `json:"namespaces,omitempty" yaml:"namespaces"`
+	// RetryAfter is the hint in seconds sent to rejected clients.
+	RetryAfter int `json:"retry-after,omitempty" yaml:"retry-after"`
+	// Message replaces the default error message, e.g. to name the
+	// maintenance in progress.
+	Message string `json:"message,omitempty" yaml:"message"`
+}
+
+// ReadOnly holds the read-only switch so that it can be flipped at runtime.
+type ReadOnly struct {
+	cfg atomic.Pointer[ReadOnlyConfig]
+}
+
+func NewReadOnly(cfg ReadOnlyConfig) *ReadOnly {
+	r := &ReadOnly{}
+	r.Set(cfg)
+	return r
+}
+
+func (r *ReadOnly) Get() ReadOnlyConfig {
+	return *r.cfg.Load()
+}
+
+func (r *ReadOnly) Set(cfg ReadOnlyConfig) {
+	r.cfg.Store(&cfg)
+}
+
+// Middleware rejects mutating catalog requests with 503 while read-only mode
+// covers them. catalogName is the catalog served by this engine.
+func (r *ReadOnly) Middleware(catalogName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := r.cfg.Load()
+		if !isCatalogWrite(c) || !cfg.covers(c, catalogName) {
+			c.Next()
+			return
+		}
+
+		errModel := apierror.ErrReadOnly
+		if cfg.Message != "" {
+			errModel.Message = cfg.Message
+		}
+		if cfg.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(cfg.RetryAfter))
+		}
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, apierror.NewResponse(c, errModel))
+	}
+}
+
+// isCatalogWrite reports whether the request is a mutating call against the
+// Iceberg REST API. Reads and requests outside /v1 are never blocked.
+func isCatalogWrite(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return strings.HasPrefix(c.FullPath(), "/v1/")
+}
+
+func (cfg *ReadOnlyConfig) covers(c *gin.Context, catalogName string) bool {
+	if cfg.Enabled || slices.Contains(cfg.Catalogs, catalogName) {
+		return true
+	}
+	if len(cfg.Namespaces) == 0 {
+		return false
+	}
+
+	for _, namespace := range requestNamespaces(c) {
+		for _, scope := range cfg.Namespaces {
+			if hasNamespacePrefix(namespace, strings.Split(scope, ".")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requestNamespaces returns the namespaces a write touches. Creating a
+// namespace and renaming a table carry them in the body, which is restored
+// for the handler after peeking.
+func requestNamespaces(c *gin.Context) [][]string {
+	if namespace := c.Param("namespace"); namespace != "" {
+		return [][]string{strings.Split(namespace, namespaceSeparator)}
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		// Hand the error on to the handler, e.g. for a body over the limit.
+		c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+		return nil
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	switch c.FullPath() {
+	case "/v1/namespaces":
+		var req struct {
+			Namespace []string `json:"namespace"`
+		}
+		if json.Unmarshal(body, &req) == nil {
+			return [][]string{req.Namespace}
+		}
+	case "/v1/tables/rename":
+		type identifier struct {
+			Namespace []string `json:"namespace"`
+		}
+		var req struct {
+			Source      identifier `json:"source"`
+			Destination identifier `json:"destination"`
+		}
+		if json.Unmarshal(body, &req) == nil {
+			return [][]string{req.Source.Namespace, req.Destination.Namespace}
+		}
+	}
+	return nil
+}
+
+func hasNamespacePrefix(namespace, prefix []string) bool {
+	return len(namespace) >= len(prefix) && slices.Equal(namespace[:len(prefix)], prefix)
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/phases"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+	"github.com/xixipi-lining/iceberg-rest-catalog/metrics"
+)
+
+const (
+	SlowReasonLatency = "latency"
+	SlowReasonSize    = "size"
+)
+
+type SlowRequestConfig struct {
+	// Latency flags requests taking longer. Zero disables the check.
+	Latency time.Duration `yaml:"latency"`
+	// ResponseSize flags responses larger than this many bytes. Zero disables
+	// the check.
+	ResponseSize int `yaml:"response-size"`
+}
+
+func (cfg SlowRequestConfig) Enabled() bool {
+	return cfg.Latency > 0 || cfg.ResponseSize > 0
+}
+
+// SlowRequests logs and counts requests over the configured thresholds, with
+// the time the handler spent per phase to tell backend, marshalling and
+// network time apart.
+func SlowRequests(cfg SlowRequestConfig, log logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		phases := phases.Track(c)
+
+		c.Next()
+
+		latency := time.Since(start)
+		size := c.Writer.Size()
+
+		var reasons []string
+		if cfg.Latency > 0 && latency > cfg.Latency {
+			reasons = append(reasons, SlowReasonLatency)
+		}
+		if cfg.ResponseSize > 0 && size > cfg.ResponseSize {
+			reasons = append(reasons, SlowReasonSize)
+		}
+		if len(reasons) == 0 {
+			return
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		for _, reason := range reasons {
+			metrics.RecordSlowRequest(route, reason)
+		}
+
+		fields := []logger.Field{
+			{Key: "requestID", Value: c.GetString("requestID")},
+			{Key: "route", Value: route},
+			{Key: "method", Value: c.Request.Method},
+			{Key: "principal", Value: c.GetString("principal")},
+			{Key: "status", Value: c.Writer.Status()},
+			{Key: "latency", Value: latency.String()},
+			{Key: "size", Value: size},
+			{Key: "reasons", Value: reasons},
+		}
+		if namespace := c.Param("namespace"); namespace != "" {
+			fields = append(fields, logger.Field{Key: "namespace", Value: strings.ReplaceAll(namespace, namespaceSeparator, ".")})
+		}
+		if table := c.Param("table"); table != "" {
+			fields = append(fields, logger.Field{Key: "table", Value: table})
+		}
+		for phase, d := range phases.Durations() {
+			fields = append(fields, logger.Field{Key: "phase." + phase, Value: d.String()})
+		}
+
+		log.Warn("slow request", fields...)
+	}
+}
@@ -0,0 +1,82 @@
+// Package phases times the phases of a request, such as the backend call and
+// marshalling, for slow request reports.
+package phases
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Phases of a request that are timed.
+const (
+	// Queue is the time a commit waited for its turn in the commit queue.
+	Queue   = "queue"
+	Backend = "backend"
+	Marshal = "marshal"
+	Write   = "write"
+)
+
+const key = "phases"
+
+// Timings accumulates the time a request spent in each phase.
+type Timings struct {
+	mu        sync.Mutex
+	durations map[string]time.Duration
+}
+
+// Track enables phase timing for the request and returns the recorder the
+// handlers will fill in.
+func Track(c *gin.Context) *Timings {
+	t := &Timings{durations: map[string]time.Duration{}}
+	c.Set(key, t)
+	return t
+}
+
+// Durations returns a copy of the time spent per phase.
+func (t *Timings) Durations() map[string]time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	durations := make(map[string]time.Duration, len(t.durations))
+	for phase, d := range t.durations {
+		durations[phase] = d
+	}
+	return durations
+}
+
+// Start starts timing phase and returns the function that stops it. It costs
+// nothing beyond a map lookup when tracking is off.
+func Start(c *gin.Context, phase string) func() {
+	t, ok := timings(c)
+	if !ok {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		t.add(phase, time.Since(start))
+	}
+}
+
+// Add counts d towards phase, for time measured by the caller.
+func Add(c *gin.Context, phase string, d time.Duration) {
+	if t, ok := timings(c); ok {
+		t.add(phase, d)
+	}
+}
+
+func timings(c *gin.Context) (*Timings, bool) {
+	v, ok := c.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return v.(*Timings), true
+}
+
+func (t *Timings) add(phase string, d time.Duration) {
+	t.mu.Lock()
+	t.durations[phase] += d
+	t.mu.Unlock()
+}
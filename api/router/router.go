@@ -2,7 +2,9 @@ package router
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/admin"
 	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/metrics"
 )
 
 // Setup configures routes
@@ -53,3 +55,62 @@ func Setup(engine *gin.Engine, handler *handlers.CatalogHandler) *gin.Engine {
 
 	return engine
 }
+
+// SetupAdmin configures operational routes
+func SetupAdmin(engine *gin.Engine, handler *admin.Handler) *gin.Engine {
+	group := engine.Group("/admin")
+	{
+		setupReadOnly(group, handler)
+		group.GET("/log-level", handler.GetLogLevel)
+		group.PUT("/log-level", handler.SetLogLevel)
+		group.GET("/build-info", handler.BuildInfo)
+		group.GET("/config", handler.GetConfig)
+		group.GET("/goroutines", handler.Goroutines)
+	}
+
+	pprof := engine.Group("/debug/pprof")
+	{
+		pprof.GET("/", handler.Pprof)
+		pprof.GET("/:name", handler.Pprof)
+		pprof.POST("/:name", handler.Pprof)
+	}
+
+	return engine
+}
+
+// SetupAudit serves the audit log, which tells who changed what. middleware
+// guards it, e.g. with authentication on the catalog listener.
+func SetupAudit(engine *gin.Engine, handler *handlers.CatalogHandler, middleware ...gin.HandlerFunc) *gin.Engine {
+	engine.Group("/audit", middleware...).GET("/events", handler.ListAuditEvents)
+
+	return engine
+}
+
+// SetupReadOnlySwitch serves only the read-only switch of the admin routes,
+// guarded by auth, for the catalog listener when the admin listener is
+// disabled.
+func SetupReadOnlySwitch(engine *gin.Engine, handler *admin.Handler, auth gin.HandlerFunc) *gin.Engine {
+	setupReadOnly(engine.Group("/admin", auth), handler)
+
+	return engine
+}
+
+func setupReadOnly(group *gin.RouterGroup, handler *admin.Handler) {
+	group.GET("/read-only", handler.GetReadOnly)
+	group.PUT("/read-only", handler.SetReadOnly)
+}
+
+// SetupMetrics exposes Prometheus metrics on path
+func SetupMetrics(engine *gin.Engine, path string) *gin.Engine {
+	engine.GET(path, gin.WrapH(metrics.Handler()))
+
+	return engine
+}
+
+// SetupHealth configures liveness and readiness probes
+func SetupHealth(engine *gin.Engine, handler *handlers.HealthHandler) *gin.Engine {
+	engine.GET("/healthz", handler.Liveness)
+	engine.GET("/readyz", handler.Readiness)
+
+	return engine
+}
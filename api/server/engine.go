@@ -0,0 +1,87 @@
+package server
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+	"github.com/xixipi-lining/iceberg-rest-catalog/redact"
+	"github.com/xixipi-lining/iceberg-rest-catalog/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// EngineConfig holds the config sections that shape the middleware of the
+// catalog API.
+type EngineConfig struct {
+	Tracing         tracing.Config
+	Metrics         bool
+	SlowRequests    middleware.SlowRequestConfig
+	MaxBodyBytes    int64
+	CORS            middleware.CORSConfig
+	Compression     middleware.CompressionConfig
+	PrincipalHeader string
+	RateLimit       middleware.RateLimitConfig
+	// Catalog is the name of the served catalog, which read-only mode can be
+	// limited to.
+	Catalog string
+}
+
+// DefaultEngineConfig returns the middleware settings of a config that
+// doesn't set them.
+func DefaultEngineConfig() EngineConfig {
+	return EngineConfig{
+		Metrics: true,
+		SlowRequests: middleware.SlowRequestConfig{
+			Latency:      2 * time.Second,
+			ResponseSize: 8 << 20,
+		},
+		MaxBodyBytes: 64 << 20,
+		CORS: middleware.CORSConfig{
+			Enabled: true,
+		},
+		Compression: middleware.CompressionConfig{
+			Enabled:        true,
+			MinSize:        1 << 10,
+			MaxRequestSize: 64 << 20,
+		},
+	}
+}
+
+// NewEngine returns an engine running the middleware of the catalog API, in
+// the order requests pass it. The caller adds the routes.
+//
+// Requests are logged to log, or to accessLog if request logs have outputs of
+// their own. Panics are written through redactor, drainer turns requests away
+// once shutdown has begun, and readOnly rejects writes while it is on.
+func NewEngine(cfg EngineConfig, log, accessLog logger.Logger, redactor *redact.Redactor, drainer *Drainer, readOnly *middleware.ReadOnly) (*gin.Engine, error) {
+	engine := gin.New()
+	if cfg.Tracing.Enabled() {
+		engine.Use(otelgin.Middleware(cfg.Tracing.ServiceName))
+	}
+	engine.Use(middleware.LoggerWithAccessLog(log.Named("http"), accessLog.Named("http")))
+	if cfg.Metrics {
+		engine.Use(middleware.Metrics())
+	}
+	if cfg.SlowRequests.Enabled() {
+		engine.Use(middleware.SlowRequests(cfg.SlowRequests, log.Named("http")))
+	}
+	// Requests arriving after shutdown began are still logged and counted.
+	engine.Use(drainer.Middleware())
+	engine.Use(middleware.BodyLimit(cfg.MaxBodyBytes))
+	if cfg.CORS.Enabled {
+		corsMiddleware, err := middleware.CORS(cfg.CORS)
+		if err != nil {
+			return nil, err
+		}
+		engine.Use(corsMiddleware)
+	}
+	engine.Use(gin.RecoveryWithWriter(redactor.Writer(gin.DefaultErrorWriter)))
+	if cfg.Compression.Enabled {
+		engine.Use(middleware.Compression(cfg.Compression))
+	}
+	engine.Use(middleware.Principal(cfg.PrincipalHeader))
+	engine.Use(middleware.RateLimit(cfg.RateLimit))
+	engine.Use(readOnly.Middleware(cfg.Catalog))
+	return engine, nil
+}
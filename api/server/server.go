@@ -0,0 +1,94 @@
+// Package server builds the HTTP listeners and drains them on shutdown.
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/apierror"
+)
+
+type Config struct {
+	// ReadHeaderTimeout bounds reading the request headers, ReadTimeout the
+	// whole request including the body.
+	ReadHeaderTimeout time.Duration `yaml:"read-header-timeout"`
+	ReadTimeout       time.Duration `yaml:"read-timeout"`
+	// WriteTimeout bounds the time from the end of the request headers to the
+	// end of the response, so it must cover the slowest commit.
+	WriteTimeout time.Duration `yaml:"write-timeout"`
+	// IdleTimeout bounds how long a keep-alive connection waits for the next
+	// request.
+	IdleTimeout time.Duration `yaml:"idle-timeout"`
+	// MaxHeaderBytes bounds the request line and headers. Larger requests
+	// get 431 Request Header Fields Too Large.
+	MaxHeaderBytes int `yaml:"max-header-bytes"`
+	// MaxBodyBytes bounds request bodies as sent, before decompression.
+	// Larger requests get 413 Request Entity Too Large. Zero means no limit.
+	MaxBodyBytes int64 `yaml:"max-body-bytes"`
+	// ShutdownTimeout bounds how long in-flight requests get to finish once
+	// the server is stopping. Connections still open then are closed.
+	ShutdownTimeout time.Duration `yaml:"shutdown-timeout"`
+}
+
+// New returns a server for handler with the configured timeouts and header
+// limit.
+func New(addr string, handler http.Handler, cfg Config) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// Drainer turns requests away once shutdown has begun, so that requests
+// arriving on kept-alive connections don't start work the server won't wait
+// for.
+//
+// The zero value is ready to use.
+type Drainer struct {
+	draining atomic.Bool
+}
+
+// Draining reports whether shutdown has begun.
+func (d *Drainer) Draining() bool {
+	return d.draining.Load()
+}
+
+// Middleware answers 503 with "Connection: close" while draining.
+func (d *Drainer) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !d.draining.Load() {
+			c.Next()
+			return
+		}
+
+		c.Header("Connection", "close")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, apierror.NewResponse(c, apierror.ErrShuttingDown))
+	}
+}
+
+// Shutdown stops accepting connections and waits up to timeout for in-flight
+// requests, such as commits, to finish. Connections still active after that
+// are closed. A timeout of zero waits indefinitely.
+func (d *Drainer) Shutdown(srv *http.Server, timeout time.Duration) error {
+	d.draining.Store(true)
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		srv.Close()
+		return err
+	}
+	return nil
+}
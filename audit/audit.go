@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	OpCreateNamespace  = "CreateNamespace"
+	OpDropNamespace    = "DropNamespace"
+	OpUpdateProperties = "UpdateProperties"
+	OpCreateTable      = "CreateTable"
+	OpUpdateTable      = "UpdateTable"
+	OpDropTable        = "DropTable"
+	OpRenameTable      = "RenameTable"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+const defaultQueryLimit = 100
+
+type Config struct {
+	// Sink selects where events are written: "jsonl", "sqlite" or empty to
+	// disable auditing.
+	Sink string `yaml:"sink"`
+	Path string `yaml:"path"`
+}
+
+// Event describes a single mutating catalog call.
+type Event struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request-id"`
+	Principal string    `json:"principal"`
+	Operation string    `json:"operation"`
+
+	Namespace []string `json:"namespace"`
+	Table     string   `json:"table,omitempty"`
+
+	// ToNamespace and ToTable hold the destination of a rename.
+	ToNamespace []string `json:"to-namespace,omitempty"`
+	ToTable     string   `json:"to-table,omitempty"`
+
+	// Updates summarizes the applied changes by their update action.
+	Updates []string `json:"updates,omitempty"`
+
+	OldMetadataLocation string `json:"old-metadata-location,omitempty"`
+	NewMetadataLocation string `json:"new-metadata-location,omitempty"`
+
+	Status  int    `json:"status"`
+	Outcome string `json:"outcome"`
+}
+
+// Filter selects events from a sink. Zero values match everything.
+type Filter struct {
+	Namespace []string
+	Table     string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+func (f Filter) limit() int {
+	if f.Limit <= 0 {
+		return defaultQueryLimit
+	}
+	return f.Limit
+}
+
+// Match reports whether the event is selected by the filter. A rename
+// matches on either its source or its destination.
+func (f Filter) Match(e Event) bool {
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return f.matchIdent(e.Namespace, e.Table) || f.matchIdent(e.ToNamespace, e.ToTable)
+}
+
+func (f Filter) matchIdent(namespace []string, table string) bool {
+	if f.Namespace != nil && !slices.Equal(f.Namespace, namespace) {
+		return false
+	}
+	if f.Table != "" && f.Table != table {
+		return false
+	}
+	return true
+}
+
+// Sink persists audit events and answers queries over them.
+type Sink interface {
+	Record(ctx context.Context, event Event) error
+	// Query returns the matching events, most recent first.
+	Query(ctx context.Context, filter Filter) ([]Event, error)
+	Close() error
+}
+
+// NewSink opens the sink described by cfg. It returns a nil sink when
+// auditing is disabled.
+func NewSink(cfg *Config) (Sink, error) {
+	var (
+		sink Sink
+		err  error
+	)
+	switch cfg.Sink {
+	case "":
+		return nil, nil
+	case "jsonl":
+		sink, err = NewJSONLSink(cfg.Path)
+	case "sqlite":
+		sink, err = NewSQLiteSink(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unknown audit sink %q", cfg.Sink)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
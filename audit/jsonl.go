@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+)
+
+// JSONLSink appends one JSON encoded event per line to a file.
+type JSONLSink struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+func NewJSONLSink(path string) (*JSONLSink, error) {
+	if path == "" {
+		return nil, errors.New("audit: jsonl sink requires a path")
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLSink{path: path, file: file}, nil
+}
+
+func (s *JSONLSink) Record(_ context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(line)
+	return err
+}
+
+func (s *JSONLSink) Query(ctx context.Context, filter Filter) ([]Event, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, err
+		}
+		if filter.Match(event) {
+			events = append(events, event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Lines are appended in time order, so the newest events are at the end.
+	slices.Reverse(events)
+	if len(events) > filter.limit() {
+		events = events[:filter.limit()]
+	}
+	return events, nil
+}
+
+func (s *JSONLSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,188 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const createEventsTable = `
+CREATE TABLE IF NOT EXISTS audit_events (
+	id                    INTEGER PRIMARY KEY AUTOINCREMENT,
+	time                  INTEGER NOT NULL,
+	request_id            TEXT NOT NULL,
+	principal             TEXT NOT NULL,
+	operation             TEXT NOT NULL,
+	namespace             TEXT NOT NULL,
+	table_name            TEXT NOT NULL,
+	to_namespace          TEXT NOT NULL,
+	to_table_name         TEXT NOT NULL,
+	updates               TEXT NOT NULL,
+	old_metadata_location TEXT NOT NULL,
+	new_metadata_location TEXT NOT NULL,
+	status                INTEGER NOT NULL,
+	outcome               TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_events_table_idx ON audit_events (namespace, table_name, time);
+CREATE INDEX IF NOT EXISTS audit_events_to_table_idx ON audit_events (to_namespace, to_table_name, time);
+`
+
+// SQLiteSink stores events in a SQLite database so they can be queried by
+// table and time range without scanning the whole log.
+type SQLiteSink struct {
+	db *sql.DB
+}
+
+func NewSQLiteSink(path string) (*SQLiteSink, error) {
+	if path == "" {
+		return nil, errors.New("audit: sqlite sink requires a path")
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(createEventsTable); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteSink{db: db}, nil
+}
+
+func (s *SQLiteSink) Record(ctx context.Context, event Event) error {
+	namespace, err := encodeNamespace(event.Namespace)
+	if err != nil {
+		return err
+	}
+	toNamespace, err := encodeNamespace(event.ToNamespace)
+	if err != nil {
+		return err
+	}
+	updates, err := json.Marshal(event.Updates)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO audit_events (
+			time, request_id, principal, operation, namespace, table_name,
+			to_namespace, to_table_name, updates, old_metadata_location,
+			new_metadata_location, status, outcome
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.Time.UnixNano(), event.RequestID, event.Principal, event.Operation,
+		namespace, event.Table, toNamespace, event.ToTable, string(updates),
+		event.OldMetadataLocation, event.NewMetadataLocation, event.Status, event.Outcome,
+	)
+	return err
+}
+
+func (s *SQLiteSink) Query(ctx context.Context, filter Filter) ([]Event, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if !filter.Since.IsZero() {
+		where = append(where, "time >= ?")
+		args = append(args, filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "time < ?")
+		args = append(args, filter.Until.UnixNano())
+	}
+	if filter.Namespace != nil || filter.Table != "" {
+		source, sourceArgs, err := identClause("namespace", "table_name", filter)
+		if err != nil {
+			return nil, err
+		}
+		dest, destArgs, err := identClause("to_namespace", "to_table_name", filter)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, "(("+source+") OR ("+dest+"))")
+		args = append(args, sourceArgs...)
+		args = append(args, destArgs...)
+	}
+
+	query := `
+		SELECT time, request_id, principal, operation, namespace, table_name,
+			to_namespace, to_table_name, updates, old_metadata_location,
+			new_metadata_location, status, outcome
+		FROM audit_events`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY time DESC, id DESC LIMIT ?"
+	args = append(args, filter.limit())
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var (
+			event                         Event
+			unixNano                      int64
+			namespace, toNamespace, updts string
+		)
+		err := rows.Scan(&unixNano, &event.RequestID, &event.Principal, &event.Operation,
+			&namespace, &event.Table, &toNamespace, &event.ToTable, &updts,
+			&event.OldMetadataLocation, &event.NewMetadataLocation, &event.Status, &event.Outcome)
+		if err != nil {
+			return nil, err
+		}
+		event.Time = time.Unix(0, unixNano).UTC()
+		if err := json.Unmarshal([]byte(namespace), &event.Namespace); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(toNamespace), &event.ToNamespace); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(updts), &event.Updates); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (s *SQLiteSink) Close() error {
+	return s.db.Close()
+}
+
+func identClause(namespaceCol, tableCol string, filter Filter) (string, []any, error) {
+	var (
+		clauses []string
+		args    []any
+	)
+	if filter.Namespace != nil {
+		namespace, err := encodeNamespace(filter.Namespace)
+		if err != nil {
+			return "", nil, err
+		}
+		clauses = append(clauses, namespaceCol+" = ?")
+		args = append(args, namespace)
+	}
+	if filter.Table != "" {
+		clauses = append(clauses, tableCol+" = ?")
+		args = append(args, filter.Table)
+	}
+	return strings.Join(clauses, " AND "), args, nil
+}
+
+// encodeNamespace stores namespaces as JSON arrays so that levels containing
+// separators stay unambiguous.
+func encodeNamespace(namespace []string) (string, error) {
+	if namespace == nil {
+		namespace = []string{}
+	}
+	b, err := json.Marshal(namespace)
+	return string(b), err
+}
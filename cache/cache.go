@@ -0,0 +1,303 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/table"
+	"github.com/xixipi-lining/iceberg-rest-catalog/commit"
+	"github.com/xixipi-lining/iceberg-rest-catalog/metrics"
+)
+
+// DefaultTTL is used when Config.TTL is zero. Entries always expire, since
+// otherwise writes by other servers sharing the backend are never seen.
+const DefaultTTL = 30 * time.Second
+
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// TTL bounds how long changes made by other writers of the backend can go
+	// unnoticed. Changes made through this server invalidate entries
+	// immediately. Zero means DefaultTTL.
+	TTL time.Duration `yaml:"ttl"`
+	// MaxEntries bounds the number of cached tables, and with it the memory
+	// held by their decoded metadata.
+	MaxEntries int `yaml:"max-entries"`
+	// MaxBytes bounds the total size of the serialized metadata JSON kept
+	// for responses. The decoded tables are not counted, they are only bounded
+	// by MaxEntries.
+	MaxBytes int64 `yaml:"max-bytes"`
+}
+
+type entry struct {
+	key      string
+	table    *table.Table
+	location string
+	expires  time.Time
+	// json is the serialized metadata at location, filled on first use.
+	json []byte
+}
+
+// Catalog caches loaded tables and their serialized metadata in front of
+// another catalog. Entries are evicted least recently used first.
+type Catalog struct {
+	catalog.Catalog
+
+	cfg Config
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	bytes   int64
+	// generation changes on every invalidation so that a load racing with a
+	// commit doesn't cache the table it read before the commit.
+	generation uint64
+}
+
+func New(cat catalog.Catalog, cfg Config) *Catalog {
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+	return &Catalog{
+		Catalog: cat,
+		cfg:     cfg,
+		now:     time.Now,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+func key(identifier table.Identifier) string {
+	return strings.Join(identifier, "\x1F")
+}
+
+// LoadTable serves the table from the cache while the entry is fresh. Loads
+// with extra properties bypass the cache since they may change the FileIO.
+func (c *Catalog) LoadTable(ctx context.Context, identifier table.Identifier, props iceberg.Properties) (*table.Table, error) {
+	if len(props) > 0 {
+		return c.Catalog.LoadTable(ctx, identifier, props)
+	}
+
+	k := key(identifier)
+	if tbl, ok := c.get(k); ok {
+		metrics.RecordCacheLookup(metrics.CacheHit)
+		return tbl, nil
+	}
+	metrics.RecordCacheLookup(metrics.CacheMiss)
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	tbl, err := c.Catalog.LoadTable(ctx, identifier, props)
+	if err != nil {
+		return nil, err
+	}
+	c.put(k, tbl, generation)
+	return tbl, nil
+}
+
+// WriteMetadata writes the encoding of the metadata of identifier at
+// location to w. The bytes encode produces are kept with the cached table
+// while it still points at location. Metadata files are immutable, so bytes
+// cached for a location never go stale.
+func (c *Catalog) WriteMetadata(w io.Writer, identifier table.Identifier, location string, encode func(w io.Writer) error) error {
+	k := key(identifier)
+
+	c.mu.Lock()
+	if elem, ok := c.entries[k]; ok {
+		e := elem.Value.(*entry)
+		if e.location == location && e.json != nil {
+			c.mu.Unlock()
+			_, err := w.Write(e.json)
+			return err
+		}
+	}
+	c.mu.Unlock()
+
+	var buf bytes.Buffer
+	if err := encode(&buf); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+
+	c.mu.Lock()
+	if elem, ok := c.entries[k]; ok {
+		e := elem.Value.(*entry)
+		if e.location == location && e.json == nil {
+			e.json = data
+			c.bytes += int64(len(data))
+			c.evict()
+		}
+	}
+	c.mu.Unlock()
+
+	_, err := w.Write(data)
+	return err
+}
+
+func (c *Catalog) CreateTable(ctx context.Context, identifier table.Identifier, schema *iceberg.Schema, opts ...catalog.CreateTableOpt) (*table.Table, error) {
+	c.Invalidate(identifier)
+	return c.Catalog.CreateTable(ctx, identifier, schema, opts...)
+}
+
+// CommitTable keeps the committed table cached so that the next load through
+// this server doesn't read the metadata file again. A failed
+// commit drops the entry since a conflict means it is likely stale.
+func (c *Catalog) CommitTable(ctx context.Context, tbl *table.Table, reqs []table.Requirement, updates []table.Update) (table.Metadata, string, error) {
+	identifier := tbl.Identifier()
+
+	base := tbl
+	if !commit.ReloadsOnCommit(c.CatalogType()) {
+		// Backends such as Glue validate requirements against the table
+		// passed in, which must not be a stale cached copy.
+		fresh, err := c.Catalog.LoadTable(ctx, identifier, nil)
+		if err != nil {
+			c.Invalidate(identifier)
+			return nil, "", err
+		}
+		base = fresh
+	}
+
+	metadata, metadataLoc, err := c.Catalog.CommitTable(ctx, base, reqs, updates)
+	if err != nil {
+		c.Invalidate(identifier)
+		return nil, "", err
+	}
+
+	k := key(identifier)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+
+	elem, cached := c.entries[k]
+	var fs table.FSysF
+	switch {
+	case !commit.IsUnloaded(base):
+		fs = base.FS
+	case cached:
+		// An unloaded base has no file system. It only depends on the table,
+		// so the one of the cached copy will do.
+		fs = elem.Value.(*entry).table.FS
+	}
+	if fs == nil {
+		if cached {
+			c.remove(elem)
+		}
+	} else if !c.hasNewer(k, metadataLoc) {
+		c.store(k, table.New(identifier, metadata, metadataLoc, fs, c))
+	}
+
+	return metadata, metadataLoc, nil
+}
+
+func (c *Catalog) DropTable(ctx context.Context, identifier table.Identifier) error {
+	defer c.Invalidate(identifier)
+	return c.Catalog.DropTable(ctx, identifier)
+}
+
+func (c *Catalog) RenameTable(ctx context.Context, from, to table.Identifier) (*table.Table, error) {
+	defer c.Invalidate(from)
+	defer c.Invalidate(to)
+	return c.Catalog.RenameTable(ctx, from, to)
+}
+
+// Invalidate drops the cached table of identifier.
+func (c *Catalog) Invalidate(identifier table.Identifier) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if elem, ok := c.entries[key(identifier)]; ok {
+		c.remove(elem)
+	}
+}
+
+// Purge drops every cached table, e.g. after the catalog behind the cache was
+// reopened with different properties.
+func (c *Catalog) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	clear(c.entries)
+	c.lru.Init()
+	c.bytes = 0
+}
+
+func (c *Catalog) get(k string) (*table.Table, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*entry)
+	if c.now().After(e.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return e.table, true
+}
+
+func (c *Catalog) put(k string, tbl *table.Table, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	c.store(k, tbl)
+}
+
+// hasNewer reports whether a concurrent commit already cached a table that
+// succeeds location. c.mu must be held.
+func (c *Catalog) hasNewer(k, location string) bool {
+	elem, ok := c.entries[k]
+	if !ok {
+		return false
+	}
+	for previous := range elem.Value.(*entry).table.Metadata().PreviousFiles() {
+		if previous.MetadataFile == location {
+			return true
+		}
+	}
+	return false
+}
+
+// store caches tbl under k. c.mu must be held.
+func (c *Catalog) store(k string, tbl *table.Table) {
+	if elem, ok := c.entries[k]; ok {
+		c.remove(elem)
+	}
+	c.entries[k] = c.lru.PushFront(&entry{
+		key:      k,
+		table:    tbl,
+		location: tbl.MetadataLocation(),
+		expires:  c.now().Add(c.cfg.TTL),
+	})
+	c.evict()
+}
+
+func (c *Catalog) evict() {
+	for c.lru.Len() > 0 &&
+		((c.cfg.MaxEntries > 0 && c.lru.Len() > c.cfg.MaxEntries) ||
+			(c.cfg.MaxBytes > 0 && c.bytes > c.cfg.MaxBytes)) {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Catalog) remove(elem *list.Element) {
+	e := c.lru.Remove(elem).(*entry)
+	delete(c.entries, e.key)
+	c.bytes -= int64(len(e.json))
+}
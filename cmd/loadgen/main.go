@@ -0,0 +1,106 @@
+// Command loadgen measures the catalog server under a mix of create, load,
+// commit and list calls. By default it starts the server in-process on a
+// SQLite catalog in a temporary directory; -url targets a running server
+// instead.
+//
+//	go run ./cmd/loadgen -mix write-heavy -tables 4 -concurrency 16 -duration 30s
+//
+// With -format bench the results are printed in the Go benchmark format, so
+// that runs on two commits can be compared with benchstat.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/xixipi-lining/iceberg-rest-catalog/cache"
+	"github.com/xixipi-lining/iceberg-rest-catalog/coalesce"
+	"github.com/xixipi-lining/iceberg-rest-catalog/commit"
+	"github.com/xixipi-lining/iceberg-rest-catalog/loadtest"
+)
+
+func main() {
+	var (
+		url         = flag.String("url", "", "catalog server to drive; empty starts one in-process")
+		namespace   = flag.String("namespace", "loadtest", "namespace holding the tables")
+		mixFlag     = flag.String("mix", "balanced", "read-heavy, write-heavy, balanced or weights such as load=8,commit=2")
+		tables      = flag.Int("tables", 8, "tables loaded and committed to")
+		concurrency = flag.Int("concurrency", 8, "parallel clients")
+		duration    = flag.Duration("duration", 10*time.Second, "length of the run")
+		operations  = flag.Int("operations", 0, "stop after this many calls, 0 for no limit")
+		seed        = flag.Uint64("seed", 1, "seed of the operation sequence")
+		format      = flag.String("format", "text", "text, json or bench")
+		cached      = flag.Bool("cache", false, "in-process server: enable the metadata cache")
+		coalesced   = flag.Bool("coalesce", false, "in-process server: enable read coalescing")
+		retry       = flag.Bool("commit-retry", false, "in-process server: enable server side commit retries")
+		queue       = flag.Int("commit-queue-batch", 0, "in-process server: enable the commit queue with this max batch size")
+	)
+	flag.Parse()
+
+	if err := run(*url, *namespace, *mixFlag, *format, loadtest.Config{
+		Tables:      *tables,
+		Concurrency: *concurrency,
+		Duration:    *duration,
+		Operations:  *operations,
+		Seed:        *seed,
+	}, loadtest.ServerConfig{
+		Cache:       cache.Config{Enabled: *cached, TTL: time.Minute, MaxEntries: 10000, MaxBytes: 256 << 20},
+		Coalesce:    coalesce.Config{Enabled: *coalesced},
+		CommitRetry: commit.Config{Enabled: *retry, MaxAttempts: 4, MinBackoff: 10 * time.Millisecond, MaxBackoff: 200 * time.Millisecond},
+		CommitQueue: commit.QueueConfig{Enabled: *queue > 0, Depth: 1000, Timeout: 30 * time.Second, MaxBatch: *queue},
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "loadgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(url, namespace, mixFlag, format string, cfg loadtest.Config, serverCfg loadtest.ServerConfig) error {
+	mix, err := loadtest.ParseMix(mixFlag)
+	if err != nil {
+		return err
+	}
+	cfg.Mix = mix
+	cfg.Namespace = namespace
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if url == "" {
+		dir, err := os.MkdirTemp("", "loadgen-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		server, err := loadtest.StartServer(dir, serverCfg)
+		if err != nil {
+			return err
+		}
+		defer server.Close()
+		url = server.URL
+	}
+
+	cat, err := rest.NewCatalog(ctx, "loadgen", url)
+	if err != nil {
+		return err
+	}
+
+	report, err := loadtest.Run(ctx, cat, cfg)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		return report.WriteJSON(os.Stdout)
+	case "bench":
+		return report.WriteBench(os.Stdout, mixFlag)
+	default:
+		return report.WriteText(os.Stdout)
+	}
+}
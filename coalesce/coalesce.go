@@ -0,0 +1,184 @@
+package coalesce
+
+import (
+	"context"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/table"
+	"github.com/xixipi-lining/iceberg-rest-catalog/metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultTimeout is used when Config.Timeout is zero.
+const DefaultTimeout = 30 * time.Second
+
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// Timeout bounds a shared backend call, which no longer follows the
+	// deadline of any one caller. Zero means DefaultTimeout.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Catalog lets concurrent identical reads share a single backend call.
+//
+// The shared call runs detached from the cancellation of the caller that
+// started it, so one client giving up doesn't fail the others. Each caller
+// still returns as soon as its own context is done. Writes through this
+// catalog make later reads of what they changed start a fresh call, so a
+// client always sees its own writes.
+//
+// A shared ListTables collects the whole listing before returning it, so it
+// doesn't stream.
+type Catalog struct {
+	catalog.Catalog
+
+	timeout time.Duration
+	group   singleflight.Group
+}
+
+func New(cat catalog.Catalog, cfg Config) *Catalog {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &Catalog{Catalog: cat, timeout: cfg.Timeout}
+}
+
+const (
+	opLoadTable               = "LoadTable"
+	opLoadNamespaceProperties = "LoadNamespaceProperties"
+	opListTables              = "ListTables"
+)
+
+func do[T any](c *Catalog, ctx context.Context, operation string, key string, fn func(context.Context) (T, error)) (T, error) {
+	ch := c.group.DoChan(operation+"\x1E"+key, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+		defer cancel()
+		return fn(ctx)
+	})
+
+	select {
+	case res := <-ch:
+		if res.Shared {
+			metrics.RecordCoalesced(operation)
+		}
+		if res.Err != nil {
+			var zero T
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// forget makes later reads of key start a fresh call instead of joining one
+// that may have started before a write.
+func (c *Catalog) forget(operation, key string) {
+	c.group.Forget(operation + "\x1E" + key)
+}
+
+func key(identifier table.Identifier) string {
+	return strings.Join(identifier, "\x1F")
+}
+
+// LoadTable coalesces loads of the same table. Loads with extra properties
+// are passed through since they may configure a different FileIO.
+func (c *Catalog) LoadTable(ctx context.Context, identifier table.Identifier, props iceberg.Properties) (*table.Table, error) {
+	if len(props) > 0 {
+		return c.Catalog.LoadTable(ctx, identifier, props)
+	}
+	return do(c, ctx, opLoadTable, key(identifier), func(ctx context.Context) (*table.Table, error) {
+		return c.Catalog.LoadTable(ctx, identifier, nil)
+	})
+}
+
+// LoadNamespaceProperties returns a copy of the shared result so that callers
+// can't modify each other's properties.
+func (c *Catalog) LoadNamespaceProperties(ctx context.Context, namespace table.Identifier) (iceberg.Properties, error) {
+	props, err := do(c, ctx, opLoadNamespaceProperties, key(namespace), func(ctx context.Context) (iceberg.Properties, error) {
+		return c.Catalog.LoadNamespaceProperties(ctx, namespace)
+	})
+	return maps.Clone(props), err
+}
+
+type listing struct {
+	identifiers []table.Identifier
+	err         error
+}
+
+// ListTables collects the listing once and replays it to every waiter. A
+// failure part way through is replayed after the identifiers seen before it.
+func (c *Catalog) ListTables(ctx context.Context, namespace table.Identifier) iter.Seq2[table.Identifier, error] {
+	return func(yield func(table.Identifier, error) bool) {
+		result, err := do(c, ctx, opListTables, key(namespace), func(ctx context.Context) (listing, error) {
+			var l listing
+			for ident, err := range c.Catalog.ListTables(ctx, namespace) {
+				if err != nil {
+					l.err = err
+					break
+				}
+				l.identifiers = append(l.identifiers, ident)
+			}
+			return l, nil
+		})
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for _, ident := range result.identifiers {
+			if !yield(slices.Clone(ident), nil) {
+				return
+			}
+		}
+		if result.err != nil {
+			yield(nil, result.err)
+		}
+	}
+}
+
+// forgetTable makes later reads of identifier, and listings of its
+// namespace, start a fresh call.
+func (c *Catalog) forgetTable(identifier table.Identifier) {
+	c.forget(opLoadTable, key(identifier))
+	c.forget(opListTables, key(catalog.NamespaceFromIdent(identifier)))
+}
+
+func (c *Catalog) CreateTable(ctx context.Context, identifier table.Identifier, schema *iceberg.Schema, opts ...catalog.CreateTableOpt) (*table.Table, error) {
+	defer c.forgetTable(identifier)
+	return c.Catalog.CreateTable(ctx, identifier, schema, opts...)
+}
+
+func (c *Catalog) CommitTable(ctx context.Context, tbl *table.Table, reqs []table.Requirement, updates []table.Update) (table.Metadata, string, error) {
+	defer c.forget(opLoadTable, key(tbl.Identifier()))
+	return c.Catalog.CommitTable(ctx, tbl, reqs, updates)
+}
+
+func (c *Catalog) DropTable(ctx context.Context, identifier table.Identifier) error {
+	defer c.forgetTable(identifier)
+	return c.Catalog.DropTable(ctx, identifier)
+}
+
+func (c *Catalog) RenameTable(ctx context.Context, from, to table.Identifier) (*table.Table, error) {
+	defer c.forgetTable(from)
+	defer c.forgetTable(to)
+	return c.Catalog.RenameTable(ctx, from, to)
+}
+
+func (c *Catalog) UpdateNamespaceProperties(ctx context.Context, namespace table.Identifier, removals []string, updates iceberg.Properties) (catalog.PropertiesUpdateSummary, error) {
+	defer c.forget(opLoadNamespaceProperties, key(namespace))
+	return c.Catalog.UpdateNamespaceProperties(ctx, namespace, removals, updates)
+}
+
+func (c *Catalog) DropNamespace(ctx context.Context, namespace table.Identifier) error {
+	defer c.forget(opLoadNamespaceProperties, key(namespace))
+	defer c.forget(opListTables, key(namespace))
+	return c.Catalog.DropNamespace(ctx, namespace)
+}
@@ -0,0 +1,63 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/table"
+)
+
+// ReloadsOnCommit reports whether the backend loads the current table and
+// checks the requirements against it inside CommitTable. Those backends only
+// use the identifier of the table passed in, so the base doesn't have to be
+// loaded, or fresh.
+func ReloadsOnCommit(t catalog.Type) bool {
+	return t == catalog.SQL || t == catalog.REST
+}
+
+// Unloaded returns a base for committing to identifier on a backend that
+// reloads on commit, without reading the table's metadata first. It has no
+// metadata, location or file system.
+func Unloaded(identifier table.Identifier, cat table.CatalogIO) *table.Table {
+	return table.New(identifier, nil, "", nil, cat)
+}
+
+// IsUnloaded reports whether tbl is a base returned by Unloaded.
+func IsUnloaded(tbl *table.Table) bool {
+	return tbl.Metadata() == nil
+}
+
+// GuardUnloaded returns the updates to commit onto base. Given an unloaded
+// base for a table that doesn't exist, the SQL backend applies the updates to
+// empty metadata and creates the table, as it does for staged creates. For an
+// unloaded base, the updates are preceded by a check that fails the commit
+// with catalog.ErrNoSuchTable instead.
+func GuardUnloaded(t catalog.Type, base *table.Table, updates []table.Update) []table.Update {
+	if t != catalog.SQL || !IsUnloaded(base) {
+		return updates
+	}
+	return append([]table.Update{requireTable{identifier: base.Identifier()}}, updates...)
+}
+
+// requireTable fails when applied to the empty metadata the SQL backend
+// starts from for a missing table. Metadata of an existing table always has a
+// location.
+type requireTable struct {
+	identifier table.Identifier
+}
+
+func (requireTable) Action() string { return "require-table" }
+
+func (r requireTable) Apply(b *table.MetadataBuilder) error {
+	md, err := b.Build()
+	if err != nil {
+		return err
+	}
+	if md.Location() == "" {
+		return fmt.Errorf("%w: %s", catalog.ErrNoSuchTable, r.identifier)
+	}
+	return nil
+}
+
+func (requireTable) PostCommit(context.Context, *table.Table, *table.Table) error { return nil }
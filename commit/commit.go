@@ -0,0 +1,340 @@
+// Package commit rebases table commits that lost a race against a concurrent
+// writer onto the table's new current metadata, so that the server can retry
+// them instead of failing the client.
+package commit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	iceio "github.com/apache/iceberg-go/io"
+	"github.com/apache/iceberg-go/table"
+	"github.com/google/uuid"
+)
+
+// ErrNotRebaseable is returned when a commit can't be safely reapplied on top
+// of the current table metadata.
+var ErrNotRebaseable = errors.New("commit can't be rebased")
+
+type Config struct {
+	// Enabled retries conflicting commits whose updates are safe to reapply.
+	Enabled bool `yaml:"enabled"`
+	// MaxAttempts bounds the commit attempts including the first one.
+	MaxAttempts int           `yaml:"max-attempts"`
+	MinBackoff  time.Duration `yaml:"min-backoff"`
+	MaxBackoff  time.Duration `yaml:"max-backoff"`
+}
+
+// Backoff returns the jittered delay before the given retry, counting from 1,
+// doubling from MinBackoff up to MaxBackoff.
+func (cfg Config) Backoff(retry int) time.Duration {
+	d := cfg.MinBackoff
+	for i := 1; i < retry && d < cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, cfg.MaxBackoff)
+	if d <= 0 {
+		return 0
+	}
+	// Full jitter keeps retrying writers from colliding again in lockstep.
+	return d/2 + rand.N(d/2+1)
+}
+
+// Wait sleeps for the backoff of retry or until ctx is done.
+func (cfg Config) Wait(ctx context.Context, retry int) error {
+	timer := time.NewTimer(cfg.Backoff(retry))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rawUpdate holds the fields of the update kinds that can be rebased. The
+// update types of iceberg-go are unexported, so they are read via JSON.
+type rawUpdate struct {
+	Action             string          `json:"action"`
+	Snapshot           *table.Snapshot `json:"snapshot"`
+	RefName            string          `json:"ref-name"`
+	RefType            table.RefType   `json:"type"`
+	SnapshotID         int64           `json:"snapshot-id"`
+	MaxRefAgeMs        int64           `json:"max-ref-age-ms"`
+	MaxSnapshotAgeMs   int64           `json:"max-snapshot-age-ms"`
+	MinSnapshotsToKeep int             `json:"min-snapshots-to-keep"`
+}
+
+type rawRequirement struct {
+	Type string `json:"type"`
+	Ref  string `json:"ref"`
+}
+
+func decode[T any](v any) (T, error) {
+	var out T
+	data, err := json.Marshal(v)
+	if err != nil {
+		return out, err
+	}
+	return out, json.Unmarshal(data, &out)
+}
+
+// Rebase returns the requirements and updates to retry a conflicting commit
+// against current, the table as it is now.
+//
+// Property changes are reapplied as they are. A fast append, i.e. an
+// add-snapshot of an "append" snapshot plus the set-snapshot-ref moving its
+// branch to it, is rebased onto the branch's current head: its manifest list
+// is rewritten to the head's manifests plus the ones the append added, with a
+// new sequence number and summary totals. Every other requirement must still
+// hold against current; anything else is not rebaseable.
+func Rebase(ctx context.Context, current *table.Table, reqs []table.Requirement, updates []table.Update) ([]table.Requirement, []table.Update, error) {
+	md := current.Metadata()
+
+	var (
+		snapshot *table.Snapshot
+		ref      *rawUpdate
+		rebased  = make([]table.Update, 0, len(updates))
+	)
+	for _, u := range updates {
+		switch u.Action() {
+		case table.UpdateSetProperties, table.UpdateRemoveProperties:
+			rebased = append(rebased, u)
+		case table.UpdateAddSnapshot, table.UpdateSetSnapshotRef:
+			raw, err := decode[rawUpdate](u)
+			if err != nil {
+				return nil, nil, err
+			}
+			if raw.Action == table.UpdateAddSnapshot {
+				if snapshot != nil || raw.Snapshot == nil {
+					return nil, nil, fmt.Errorf("%w: more than one snapshot added", ErrNotRebaseable)
+				}
+				snapshot = raw.Snapshot
+			} else {
+				if ref != nil {
+					return nil, nil, fmt.Errorf("%w: more than one ref updated", ErrNotRebaseable)
+				}
+				ref = &raw
+			}
+		default:
+			return nil, nil, fmt.Errorf("%w: %s can't be reapplied", ErrNotRebaseable, u.Action())
+		}
+	}
+
+	if (snapshot == nil) != (ref == nil) || (ref != nil && (ref.SnapshotID != snapshot.SnapshotID || ref.RefType != table.BranchRef)) {
+		return nil, nil, fmt.Errorf("%w: only appends moving a branch to the added snapshot are reapplied", ErrNotRebaseable)
+	}
+
+	var rebasedReqs []table.Requirement
+	for _, r := range reqs {
+		raw, err := decode[rawRequirement](r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ref != nil && raw.Type == "assert-ref-snapshot-id" && raw.Ref == ref.RefName {
+			// Replaced by an assertion on the head the append is rebased on.
+			continue
+		}
+		if err := r.Validate(md); err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrNotRebaseable, err)
+		}
+		rebasedReqs = append(rebasedReqs, r)
+	}
+
+	if snapshot == nil {
+		return rebasedReqs, rebased, nil
+	}
+
+	var headID *int64
+	head := md.SnapshotByName(ref.RefName)
+	if head != nil {
+		headID = &head.SnapshotID
+	}
+	rebasedReqs = append(rebasedReqs, table.AssertRefSnapshotID(ref.RefName, headID))
+
+	rebasedSnapshot, err := rebaseAppend(ctx, current, snapshot, head)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rebased = append(rebased,
+		table.NewAddSnapshotUpdate(rebasedSnapshot),
+		table.NewSetSnapshotRefUpdate(ref.RefName, rebasedSnapshot.SnapshotID, table.BranchRef,
+			ref.MaxRefAgeMs, ref.MaxSnapshotAgeMs, ref.MinSnapshotsToKeep),
+	)
+	return rebasedReqs, rebased, nil
+}
+
+func rebaseAppend(ctx context.Context, current *table.Table, snapshot *table.Snapshot, head *table.Snapshot) (*table.Snapshot, error) {
+	md := current.Metadata()
+
+	if snapshot.Summary == nil || snapshot.Summary.Operation != table.OpAppend {
+		return nil, fmt.Errorf("%w: only append snapshots are reapplied", ErrNotRebaseable)
+	}
+	if head != nil && snapshot.ParentSnapshotID != nil && *snapshot.ParentSnapshotID == head.SnapshotID {
+		// The branch didn't move, the conflict was elsewhere.
+		return snapshot, nil
+	}
+	if err := checkIntervening(md, head, snapshot.ParentSnapshotID); err != nil {
+		return nil, err
+	}
+
+	fs, err := current.FS(ctx)
+	if err != nil {
+		return nil, err
+	}
+	wfs, ok := fs.(iceio.WriteFileIO)
+	if !ok {
+		return nil, fmt.Errorf("%w: table FileIO is read-only", ErrNotRebaseable)
+	}
+
+	version := md.Version()
+	var sequenceNumber int64
+	if version > 1 {
+		sequenceNumber = md.LastSequenceNumber() + 1
+	}
+
+	added, err := snapshot.Manifests(fs)
+	if err != nil {
+		return nil, err
+	}
+	manifests := make([]iceberg.ManifestFile, 0, len(added))
+	for _, m := range added {
+		if m.SnapshotID() != snapshot.SnapshotID {
+			// Carried over from the stale parent, replaced by the head's.
+			continue
+		}
+		if m.ManifestContent() != iceberg.ManifestContentData || m.ExistingDataFiles() > 0 || m.DeletedDataFiles() > 0 {
+			return nil, fmt.Errorf("%w: snapshot is not a fast append", ErrNotRebaseable)
+		}
+		manifests = append(manifests, withSequenceNumber(version, m, sequenceNumber))
+	}
+
+	var parentSummary *table.Summary
+	if head != nil {
+		headManifests, err := head.Manifests(fs)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, headManifests...)
+		parentSummary = head.Summary
+	}
+
+	var buf bytes.Buffer
+	var parentID *int64
+	if head != nil {
+		parentID = &head.SnapshotID
+	}
+	if err := iceberg.WriteManifestList(version, &buf, snapshot.SnapshotID, parentID, &sequenceNumber, manifests); err != nil {
+		return nil, err
+	}
+
+	// Cut at the last slash rather than using path.Dir, which would collapse
+	// the "//" of a URL scheme.
+	dir := snapshot.ManifestList[:strings.LastIndex(snapshot.ManifestList, "/")+1]
+	location := dir + fmt.Sprintf("snap-%d-rebased-%s.avro", snapshot.SnapshotID, uuid.NewString())
+	if err := writeFile(wfs, location, buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	rebased := *snapshot
+	rebased.ParentSnapshotID = parentID
+	rebased.SequenceNumber = sequenceNumber
+	rebased.ManifestList = location
+	rebased.TimestampMs = max(snapshot.TimestampMs, time.Now().UnixMilli())
+	rebased.Summary = rebaseSummary(snapshot.Summary, parentSummary)
+	return &rebased, nil
+}
+
+// checkIntervening walks from head back to the append's original parent and
+// refuses to rebase over snapshots that added delete files, which could apply
+// to the appended rows once they get a newer sequence number.
+func checkIntervening(md table.Metadata, head *table.Snapshot, parentID *int64) error {
+	for s := head; s != nil; {
+		if parentID != nil && s.SnapshotID == *parentID {
+			return nil
+		}
+		if s.Summary != nil && s.Summary.Properties["added-delete-files"] != "" && s.Summary.Properties["added-delete-files"] != "0" {
+			return fmt.Errorf("%w: snapshot %d added delete files", ErrNotRebaseable, s.SnapshotID)
+		}
+		if s.ParentSnapshotID == nil {
+			break
+		}
+		s = md.SnapshotByID(*s.ParentSnapshotID)
+	}
+	if parentID != nil {
+		return fmt.Errorf("%w: snapshot %d is no longer an ancestor of the branch", ErrNotRebaseable, *parentID)
+	}
+	return nil
+}
+
+func withSequenceNumber(version int, m iceberg.ManifestFile, sequenceNumber int64) iceberg.ManifestFile {
+	b := iceberg.NewManifestFile(version, m.FilePath(), m.Length(), m.PartitionSpecID(), m.SnapshotID()).
+		Content(m.ManifestContent()).
+		AddedFiles(m.AddedDataFiles()).
+		ExistingFiles(m.ExistingDataFiles()).
+		DeletedFiles(m.DeletedDataFiles()).
+		AddedRows(m.AddedRows()).
+		ExistingRows(m.ExistingRows()).
+		DeletedRows(m.DeletedRows()).
+		Partitions(m.Partitions()).
+		KeyMetadata(m.KeyMetadata())
+	if version > 1 {
+		b = b.SequenceNum(sequenceNumber, sequenceNumber)
+	}
+	return b.Build()
+}
+
+// totals maps each snapshot summary total to the fields adding to and
+// removing from it.
+var totals = map[string][2]string{
+	"total-records":          {"added-records", "deleted-records"},
+	"total-files-size":       {"added-files-size", "removed-files-size"},
+	"total-data-files":       {"added-data-files", "deleted-data-files"},
+	"total-delete-files":     {"added-delete-files", "removed-delete-files"},
+	"total-position-deletes": {"added-position-deletes", "removed-position-deletes"},
+	"total-equality-deletes": {"added-equality-deletes", "removed-equality-deletes"},
+}
+
+func rebaseSummary(summary, parent *table.Summary) *table.Summary {
+	props := make(iceberg.Properties, len(summary.Properties))
+	for k, v := range summary.Properties {
+		props[k] = v
+	}
+
+	for total, fields := range totals {
+		if _, ok := props[total]; !ok {
+			continue
+		}
+		var base int64
+		if parent != nil {
+			base, _ = strconv.ParseInt(parent.Properties[total], 10, 64)
+		}
+		added, _ := strconv.ParseInt(props[fields[0]], 10, 64)
+		removed, _ := strconv.ParseInt(props[fields[1]], 10, 64)
+		props[total] = strconv.FormatInt(base+added-removed, 10)
+	}
+
+	return &table.Summary{Operation: summary.Operation, Properties: props}
+}
+
+func writeFile(fs iceio.WriteFileIO, location string, data []byte) error {
+	w, err := fs.Create(location)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,317 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/table"
+	"github.com/xixipi-lining/iceberg-rest-catalog/metrics"
+)
+
+var (
+	// ErrQueueFull is returned when a table already has the maximum number
+	// of commits waiting.
+	ErrQueueFull = errors.New("commit queue is full")
+	// ErrQueueTimeout is returned when a commit waited longer than the queue
+	// timeout for its turn.
+	ErrQueueTimeout = errors.New("timed out waiting in the commit queue")
+)
+
+type QueueConfig struct {
+	// Enabled serializes the commits made through this server per table.
+	Enabled bool `yaml:"enabled"`
+	// Depth bounds the commits waiting per table, not counting the one in
+	// progress. Zero means unbounded.
+	Depth int `yaml:"depth"`
+	// Timeout bounds how long a commit waits for its turn. Zero means no
+	// limit besides the request's own.
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxBatch is the most waiting commits applied in one backend commit.
+	// Values below 2 disable batching.
+	MaxBatch int `yaml:"max-batch"`
+}
+
+// Catalog is the part of catalog.Catalog the queue commits through.
+type Catalog interface {
+	LoadTable(ctx context.Context, identifier table.Identifier, props iceberg.Properties) (*table.Table, error)
+	CommitTable(ctx context.Context, tbl *table.Table, reqs []table.Requirement, updates []table.Update) (table.Metadata, string, error)
+}
+
+// Request is a commit submitted to the queue.
+type Request struct {
+	Base         *table.Table
+	Requirements []table.Requirement
+	Updates      []table.Update
+	// Commit commits the request on its own when its turn comes and it isn't
+	// batched with others.
+	Commit func(ctx context.Context) Result
+}
+
+type Result struct {
+	Metadata table.Metadata
+	Location string
+	// Attempts is the number of backend commits made for the request,
+	// including server side retries.
+	Attempts int
+	// Batched is the number of requests applied together in one backend
+	// commit, 1 when the request was committed alone.
+	Batched int
+	// Waited is how long the request waited in the queue for its turn.
+	Waited time.Duration
+	Err    error
+}
+
+type waiter struct {
+	ctx  context.Context
+	req  Request
+	done chan Result
+	// submitted is when the request was queued, waited how long it waited
+	// for its turn. waited is set before the result is sent on done.
+	submitted time.Time
+	waited    time.Duration
+}
+
+type tableQueue struct {
+	pending []*waiter
+	running bool
+}
+
+// Queue serializes commits per table so that writers of the same table take
+// turns instead of racing each other into conflicts, while different tables
+// still commit in parallel. Each table with waiting commits has one worker
+// goroutine, which exits once its queue is drained.
+type Queue struct {
+	cfg     QueueConfig
+	catalog Catalog
+
+	mu     sync.Mutex
+	tables map[string]*tableQueue
+}
+
+func NewQueue(cfg QueueConfig, catalog Catalog) *Queue {
+	return &Queue{cfg: cfg, catalog: catalog, tables: make(map[string]*tableQueue)}
+}
+
+func queueKey(ident table.Identifier) string {
+	return strings.Join(ident, "\x1f")
+}
+
+// Submit waits for the table's turn and commits req. It fails with
+// ErrQueueFull or ErrQueueTimeout without committing when the queue is over
+// its limits. Once the commit has started, its outcome is returned even if ctx
+// is done by then.
+func (q *Queue) Submit(ctx context.Context, req Request) Result {
+	key := queueKey(req.Base.Identifier())
+	w := &waiter{ctx: ctx, req: req, done: make(chan Result, 1), submitted: time.Now()}
+
+	q.mu.Lock()
+	tq := q.tables[key]
+	if tq == nil {
+		tq = &tableQueue{}
+		q.tables[key] = tq
+	}
+	if q.cfg.Depth > 0 && len(tq.pending) >= q.cfg.Depth {
+		q.mu.Unlock()
+		metrics.RecordCommitQueueRejected(metrics.QueueFull)
+		return Result{Err: ErrQueueFull}
+	}
+	tq.pending = append(tq.pending, w)
+	metrics.RecordCommitQueueWaiting(1)
+	if !tq.running {
+		tq.running = true
+		go q.run(key, tq)
+	}
+	q.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if q.cfg.Timeout > 0 {
+		timer := time.NewTimer(q.cfg.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case res := <-w.done:
+		res.Waited = w.waited
+		return res
+	case <-timeout:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if q.remove(tq, w) {
+		if errors.Is(err, ErrQueueTimeout) {
+			metrics.RecordCommitQueueRejected(metrics.QueueTimeout)
+		}
+		return Result{Err: err}
+	}
+	// The worker already took it, so it may be committed.
+	res := <-w.done
+	res.Waited = w.waited
+	return res
+}
+
+// remove takes w out of the queue if it hasn't been picked up yet.
+func (q *Queue) remove(tq *tableQueue, w *waiter) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := slices.Index(tq.pending, w)
+	if i < 0 {
+		return false
+	}
+	tq.pending = slices.Delete(tq.pending, i, i+1)
+	metrics.RecordCommitQueueWaiting(-1)
+	return true
+}
+
+func (q *Queue) run(key string, tq *tableQueue) {
+	for {
+		q.mu.Lock()
+		if len(tq.pending) == 0 {
+			tq.running = false
+			delete(q.tables, key)
+			q.mu.Unlock()
+			return
+		}
+		n := min(len(tq.pending), max(q.cfg.MaxBatch, 1))
+		batch := slices.Clone(tq.pending[:n])
+		tq.pending = slices.Delete(tq.pending, 0, n)
+		metrics.RecordCommitQueueWaiting(-n)
+		q.mu.Unlock()
+
+		for _, w := range batch {
+			w.waited = time.Since(w.submitted)
+		}
+
+		q.commit(batch)
+	}
+}
+
+func (q *Queue) commit(batch []*waiter) {
+	if len(batch) > 1 {
+		batch = q.commitBatch(batch)
+	}
+	for _, w := range batch {
+		// Requests are committed to the end even if their client gave up.
+		w.done <- w.req.Commit(context.WithoutCancel(w.ctx))
+	}
+}
+
+// commitBatch applies the longest prefix of batch that can be combined in a
+// single backend commit, and returns the requests left to be committed on
+// their own.
+//
+// The requests are rebased one after another onto the current table, in the
+// same way as commit retries, so only property changes and fast appends are
+// batched. The combined commit keeps every request's requirements except the
+// branch assertions on branches moved by an earlier request of the batch.
+func (q *Queue) commitBatch(batch []*waiter) []*waiter {
+	ctx := context.WithoutCancel(batch[0].ctx)
+	ident := batch[0].req.Base.Identifier()
+
+	current, err := q.catalog.LoadTable(ctx, ident, nil)
+	if err != nil {
+		return batch
+	}
+
+	var (
+		staged  = current
+		reqs    []table.Requirement
+		updates []table.Update
+		moved   = make(map[string]bool)
+		n       int
+	)
+	for _, w := range batch {
+		r, u, err := Rebase(ctx, staged, w.req.Requirements, w.req.Updates)
+		if err != nil {
+			break
+		}
+		next, err := stage(staged, u)
+		if err != nil {
+			break
+		}
+		r, err = dropMovedRefAssertions(r, moved)
+		if err != nil {
+			break
+		}
+		if err := markMovedRefs(u, moved); err != nil {
+			break
+		}
+
+		reqs = append(reqs, r...)
+		updates = append(updates, u...)
+		staged = next
+		n++
+	}
+	if n < 2 {
+		return batch
+	}
+
+	metrics.ObserveCommitBatch(n)
+	md, loc, err := q.catalog.CommitTable(ctx, current, reqs, updates)
+	if err != nil {
+		// Fall back to committing them one by one, so that one bad request
+		// doesn't fail the others.
+		return batch
+	}
+	for _, w := range batch[:n] {
+		w.done <- Result{Metadata: md, Location: loc, Attempts: 1, Batched: n}
+	}
+	return batch[n:]
+}
+
+// stage applies updates to tbl in memory, for rebasing the next request of a
+// batch onto.
+func stage(tbl *table.Table, updates []table.Update) (*table.Table, error) {
+	b, err := table.MetadataBuilderFromBase(tbl.Metadata())
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range updates {
+		if err := u.Apply(b); err != nil {
+			return nil, err
+		}
+	}
+	md, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return table.New(tbl.Identifier(), md, tbl.MetadataLocation(), tbl.FS, nil), nil
+}
+
+func dropMovedRefAssertions(reqs []table.Requirement, moved map[string]bool) ([]table.Requirement, error) {
+	kept := reqs[:0:0]
+	for _, r := range reqs {
+		raw, err := decode[rawRequirement](r)
+		if err != nil {
+			return nil, err
+		}
+		if raw.Type == "assert-ref-snapshot-id" && moved[raw.Ref] {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept, nil
+}
+
+func markMovedRefs(updates []table.Update, moved map[string]bool) error {
+	for _, u := range updates {
+		if u.Action() != table.UpdateSetSnapshotRef {
+			continue
+		}
+		raw, err := decode[rawUpdate](u)
+		if err != nil {
+			return err
+		}
+		moved[raw.RefName] = true
+	}
+	return nil
+}
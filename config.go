@@ -0,0 +1,471 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/admin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/server"
+	"github.com/xixipi-lining/iceberg-rest-catalog/audit"
+	"github.com/xixipi-lining/iceberg-rest-catalog/cache"
+	"github.com/xixipi-lining/iceberg-rest-catalog/coalesce"
+	"github.com/xixipi-lining/iceberg-rest-catalog/commit"
+	"github.com/xixipi-lining/iceberg-rest-catalog/envconfig"
+	"github.com/xixipi-lining/iceberg-rest-catalog/health"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+	"github.com/xixipi-lining/iceberg-rest-catalog/metrics"
+	"github.com/xixipi-lining/iceberg-rest-catalog/redact"
+	"github.com/xixipi-lining/iceberg-rest-catalog/reload"
+	"github.com/xixipi-lining/iceberg-rest-catalog/secret"
+	"github.com/xixipi-lining/iceberg-rest-catalog/tracing"
+	"github.com/xixipi-lining/iceberg-rest-catalog/validate"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	cfgFile = ".iceberg-go.yaml"
+	// envPrefix starts the environment variables overriding config keys.
+	envPrefix = "ICEBERG_REST"
+)
+
+type Config struct {
+	DefaultCatalog string                        `yaml:"default-catalog"`
+	Catalogs       map[string]iceberg.Properties `yaml:"catalog"`
+
+	ServerConfig handlers.Config `yaml:"server"`
+
+	LogConfig   logger.Config `yaml:"log"`
+	AuditConfig audit.Config  `yaml:"audit"`
+
+	// PrincipalHeader names the request header carrying the authenticated
+	// caller, as set by a proxy in front of the server.
+	PrincipalHeader string `yaml:"principal-header"`
+
+	RateLimit middleware.RateLimitConfig `yaml:"rate-limit"`
+	ReadOnly  middleware.ReadOnlyConfig  `yaml:"read-only"`
+	Redact    redact.Config              `yaml:"redact"`
+	CORS      middleware.CORSConfig      `yaml:"cors"`
+	Metrics   metrics.Config             `yaml:"metrics"`
+	Tracing   tracing.Config             `yaml:"tracing"`
+	Health    health.Config              `yaml:"health"`
+	Admin     admin.Config               `yaml:"admin"`
+	Cache     cache.Config               `yaml:"cache"`
+	Coalesce  coalesce.Config            `yaml:"coalesce"`
+	Commit    commit.Config              `yaml:"commit-retry"`
+	Queue     commit.QueueConfig         `yaml:"commit-queue"`
+
+	SlowRequests middleware.SlowRequestConfig `yaml:"slow-requests"`
+	Compression  middleware.CompressionConfig `yaml:"compression"`
+
+	HTTP   server.Config `yaml:"http"`
+	Reload reload.Config `yaml:"reload"`
+
+	Port int    `yaml:"port"`
+	Host string `yaml:"host"`
+}
+
+// defaultConfig holds the values of keys missing from every source.
+func defaultConfig() *Config {
+	engine := server.DefaultEngineConfig()
+	return &Config{
+		DefaultCatalog: "default",
+		LogConfig: logger.Config{
+			Debug:    true,
+			MaxSize:  100,
+			Compress: false,
+		},
+		ServerConfig: handlers.Config{
+			Defaults:  map[string]string{},
+			Overrides: map[string]string{},
+		},
+		CORS: engine.CORS,
+		Metrics: metrics.Config{
+			Enabled:         engine.Metrics,
+			Path:            "/metrics",
+			CollectInterval: time.Minute,
+		},
+		Tracing: tracing.Config{
+			ServiceName: "iceberg-rest-catalog",
+			SampleRatio: 1,
+		},
+		Health: health.Config{
+			Backend:  true,
+			Timeout:  5 * time.Second,
+			CacheTTL: 5 * time.Second,
+		},
+		SlowRequests: engine.SlowRequests,
+		Compression:  engine.Compression,
+		Commit: commit.Config{
+			MaxAttempts: 4,
+			MinBackoff:  50 * time.Millisecond,
+			MaxBackoff:  time.Second,
+		},
+		Queue: commit.QueueConfig{
+			Depth:    100,
+			Timeout:  10 * time.Second,
+			MaxBatch: 1,
+		},
+		Coalesce: coalesce.Config{
+			Enabled: true,
+			Timeout: coalesce.DefaultTimeout,
+		},
+		Cache: cache.Config{
+			TTL:        cache.DefaultTTL,
+			MaxEntries: 1000,
+			MaxBytes:   256 << 20,
+		},
+		Admin: admin.Config{
+			Host: "127.0.0.1",
+			Port: 9090,
+		},
+		HTTP: server.Config{
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       time.Minute,
+			WriteTimeout:      5 * time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    1 << 20,
+			MaxBodyBytes:      engine.MaxBodyBytes,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Reload: reload.Config{
+			Watch:    true,
+			Interval: 5 * time.Second,
+		},
+		Port: 8080,
+		Host: "127.0.0.1",
+	}
+}
+
+// engine returns the sections of cfg the catalog API middleware runs with.
+func (cfg *Config) engine() server.EngineConfig {
+	return server.EngineConfig{
+		Tracing:         cfg.Tracing,
+		Metrics:         cfg.Metrics.Enabled,
+		SlowRequests:    cfg.SlowRequests,
+		MaxBodyBytes:    cfg.HTTP.MaxBodyBytes,
+		CORS:            cfg.CORS,
+		Compression:     cfg.Compression,
+		PrincipalHeader: cfg.PrincipalHeader,
+		RateLimit:       cfg.RateLimit,
+		Catalog:         cfg.DefaultCatalog,
+	}
+}
+
+// configSource reads the config from the defaults, the file, ICEBERG_REST_*
+// environment variables and command-line flags, each overriding the ones
+// before. Reloads read it again the same way, except that the file has to
+// exist.
+type configSource struct {
+	path string
+	// optional is set when path is the default location, which may not exist.
+	optional bool
+	// flags applies the flags given on the command line.
+	flags func(*Config)
+}
+
+func (s configSource) load() (*Config, error) {
+	cfg := defaultConfig()
+
+	file, err := os.ReadFile(s.path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(file, cfg); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, fs.ErrNotExist) && s.optional:
+	default:
+		return nil, err
+	}
+
+	if err := envconfig.Apply(envPrefix, os.Environ(), cfg); err != nil {
+		return nil, err
+	}
+	if s.flags != nil {
+		s.flags(cfg)
+	}
+
+	// A config that forgot its catalog fails validation rather than serving
+	// an empty one.
+	if len(cfg.Catalogs) == 0 && s.unconfigured() {
+		props, err := localCatalog()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Catalogs = map[string]iceberg.Properties{cfg.DefaultCatalog: props}
+	}
+	return cfg, nil
+}
+
+// reload reads the config again for a running server. The file must exist,
+// even at the default location, so that a file briefly missing during a
+// reload fails it instead of swapping the served catalog for localCatalog.
+func (s configSource) reload() (*Config, error) {
+	s.optional = false
+	return s.load()
+}
+
+// unconfigured reports whether the server runs without a config file and
+// without ICEBERG_REST_* variables, and so serves localCatalog.
+func (s configSource) unconfigured() bool {
+	if _, err := os.Stat(s.path); err == nil || !s.optional {
+		return false
+	}
+	return !slices.ContainsFunc(os.Environ(), func(kv string) bool {
+		return strings.HasPrefix(kv, envPrefix+"_")
+	})
+}
+
+// localCatalog is served when nothing is configured at all: a SQLite catalog
+// with its warehouse in the working directory.
+func localCatalog() (iceberg.Properties, error) {
+	warehouse, err := filepath.Abs("warehouse")
+	if err != nil {
+		return nil, err
+	}
+	return iceberg.Properties{
+		"type":                "sql",
+		"uri":                 "file:iceberg_catalog.db",
+		"sql.driver":          "sqlite3",
+		"sql.dialect":         "sqlite",
+		"init_catalog_tables": "true",
+		"warehouse":           "file://" + warehouse,
+	}, nil
+}
+
+// parseFlags reads the command line into fset, which may hold further flags.
+// Only flags that are given override the config.
+func parseFlags(fset *flag.FlagSet, args []string) (configSource, error) {
+	var (
+		path           = fset.String("config", "", "config file (default $GOICEBERG_HOME/"+cfgFile+" or ~/"+cfgFile+")")
+		host           = fset.String("host", "", "address to listen on")
+		port           = fset.Int("port", 0, "port to listen on")
+		defaultCatalog = fset.String("default-catalog", "", "catalog to serve")
+	)
+	if err := fset.Parse(args); err != nil {
+		return configSource{}, err
+	}
+	if fset.NArg() > 0 {
+		return configSource{}, fmt.Errorf("unexpected arguments: %v", fset.Args())
+	}
+
+	source := configSource{path: *path}
+	if source.path == "" {
+		var err error
+		if source.path, err = defaultConfigPath(); err != nil {
+			return configSource{}, err
+		}
+		source.optional = true
+	}
+
+	var set []func(*Config)
+	fset.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "host":
+			set = append(set, func(cfg *Config) { cfg.Host = *host })
+		case "port":
+			set = append(set, func(cfg *Config) { cfg.Port = *port })
+		case "default-catalog":
+			set = append(set, func(cfg *Config) { cfg.DefaultCatalog = *defaultCatalog })
+		}
+	})
+	source.flags = func(cfg *Config) {
+		for _, fn := range set {
+			fn(cfg)
+		}
+	}
+	return source, nil
+}
+
+// defaultConfigPath returns the config file read when -config isn't given.
+func defaultConfigPath() (string, error) {
+	if dir := os.Getenv("GOICEBERG_HOME"); dir != "" {
+		return filepath.Join(dir, cfgFile), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, cfgFile), nil
+}
+
+// secrets resolves references such as ${env:NAME} in catalog properties.
+// Providers for further schemes are registered on it before the config is
+// loaded.
+var secrets = secret.NewResolver()
+
+// resolveCatalog returns props with their secret references resolved. The
+// resolved values are registered with redactor before they are handed to
+// anything that could log them.
+func resolveCatalog(ctx context.Context, props map[string]string, redactor *redact.Redactor) (map[string]string, error) {
+	resolved, values, err := secrets.Properties(ctx, props)
+	redactor.AddValues(values...)
+	if err != nil {
+		return nil, err
+	}
+	redactor.AddSecrets(resolved)
+	return resolved, nil
+}
+
+// Validate checks the whole config without connecting to anything, and
+// reports every problem with the YAML path of its key.
+func (cfg *Config) Validate(opts ...validate.Option) error {
+	r := validate.NewReport(opts...)
+
+	if _, ok := cfg.Catalogs[cfg.DefaultCatalog]; !ok {
+		r.Add("default-catalog", "catalog %q is not defined under catalog", cfg.DefaultCatalog)
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Catalogs)) {
+		path := validate.Join("catalog", name)
+		validate.Catalog(&r, path, cfg.Catalogs[name])
+		for _, key := range slices.Sorted(maps.Keys(cfg.Catalogs[name])) {
+			if err := secrets.Check(cfg.Catalogs[name][key]); err != nil {
+				r.Add(validate.Join(path, key), "%s", err)
+			}
+		}
+	}
+	for i, name := range cfg.ReadOnly.Catalogs {
+		if _, ok := cfg.Catalogs[name]; !ok {
+			r.Add(validate.Index("read-only.catalogs", i), "catalog %q is not defined under catalog", name)
+		}
+	}
+
+	validate.Port(&r, "port", cfg.Port)
+	if cfg.Admin.Enabled {
+		validate.Port(&r, "admin.port", cfg.Admin.Port)
+		if cfg.Admin.Token == "" {
+			r.Add("admin.token", "is required when the admin listener is enabled")
+		}
+		if cfg.Admin.Port == cfg.Port && (cfg.Admin.Host == cfg.Host || isWildcard(cfg.Admin.Host) || isWildcard(cfg.Host)) {
+			r.Add("admin.port", "conflicts with the catalog listener on port %d", cfg.Port)
+		}
+	}
+
+	r.Merge("log", cfg.LogConfig.Validate(opts...))
+	switch cfg.AuditConfig.Sink {
+	case "":
+	case "jsonl", "sqlite":
+		validate.Writable(&r, "audit.path", cfg.AuditConfig.Path)
+	default:
+		r.Add("audit.sink", "unknown audit sink %q, expected jsonl or sqlite", cfg.AuditConfig.Sink)
+	}
+	if _, err := redact.New(cfg.Redact); err != nil {
+		r.Add("redact.patterns", "%s", err)
+	}
+
+	if cfg.CORS.Enabled {
+		if _, err := middleware.CORS(cfg.CORS); err != nil {
+			r.Add("cors", "%s", strings.TrimPrefix(err.Error(), "cors: "))
+		}
+	}
+	r.Merge("rate-limit", cfg.RateLimit.Validate())
+	if cfg.Compression.Enabled {
+		r.Merge("compression", cfg.Compression.Validate())
+	}
+
+	if cfg.Metrics.Enabled && !strings.HasPrefix(cfg.Metrics.Path, "/") {
+		r.Add("metrics.path", "must start with /")
+	}
+	switch cfg.Tracing.Exporter {
+	case "", "otlp", "stdout":
+	default:
+		r.Add("tracing.exporter", "unknown trace exporter %q, expected otlp or stdout", cfg.Tracing.Exporter)
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		r.Add("tracing.sample-ratio", "must be between 0 and 1")
+	}
+
+	if cfg.Commit.Enabled {
+		if cfg.Commit.MaxAttempts < 1 {
+			r.Add("commit-retry.max-attempts", "must be at least 1")
+		}
+		if cfg.Commit.MaxBackoff < cfg.Commit.MinBackoff {
+			r.Add("commit-retry.max-backoff", "is below min-backoff")
+		}
+	}
+	if cfg.Queue.Enabled {
+		if cfg.Queue.Depth < 1 {
+			r.Add("commit-queue.depth", "must be at least 1")
+		}
+		if cfg.Queue.MaxBatch < 1 {
+			r.Add("commit-queue.max-batch", "must be at least 1")
+		}
+	}
+	if cfg.Coalesce.Enabled && cfg.Coalesce.Timeout < 0 {
+		r.Add("coalesce.timeout", "must not be negative, 0 means the default of %s", coalesce.DefaultTimeout)
+	}
+	if cfg.Cache.Enabled && cfg.Cache.TTL < 0 {
+		r.Add("cache.ttl", "must not be negative, 0 means the default of %s", cache.DefaultTTL)
+	}
+
+	for _, timeout := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"http.read-header-timeout", cfg.HTTP.ReadHeaderTimeout},
+		{"http.read-timeout", cfg.HTTP.ReadTimeout},
+		{"http.write-timeout", cfg.HTTP.WriteTimeout},
+		{"http.idle-timeout", cfg.HTTP.IdleTimeout},
+		{"http.shutdown-timeout", cfg.HTTP.ShutdownTimeout},
+	} {
+		if timeout.value < 0 {
+			r.Add(timeout.key, "must not be negative")
+		}
+	}
+	if cfg.Reload.Watch && cfg.Reload.Interval <= 0 {
+		r.Add("reload.interval", "must be positive when watch is enabled")
+	}
+
+	return r.Err()
+}
+
+func isWildcard(host string) bool {
+	return host == "" || host == "0.0.0.0" || host == "::"
+}
+
+// validateConfig runs the validate-config subcommand. It loads the config the
+// way the server does and reports every problem, so that CI can check a
+// config before it is deployed. It returns the exit code.
+func validateConfig(args []string, stdout, stderr io.Writer) int {
+	fset := flag.NewFlagSet("iceberg-rest-catalog validate-config", flag.ContinueOnError)
+	fset.SetOutput(stderr)
+	skipFilesystem := fset.Bool("skip-filesystem", false, "don't check that log and audit files can be written on this machine")
+	source, err := parseFlags(fset, args)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case err != nil:
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	cfg, err := source.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load config: %s\n", err)
+		return 1
+	}
+	var opts []validate.Option
+	if *skipFilesystem {
+		opts = append(opts, validate.SkipFilesystem())
+	}
+	if err := cfg.Validate(opts...); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if source.unconfigured() {
+		fmt.Fprintf(stderr, "warning: no config file at %s and no %s_* variables set, the server would serve a local SQLite catalog\n", source.path, envPrefix)
+	}
+	fmt.Fprintln(stdout, "config is valid")
+	return 0
+}
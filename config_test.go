@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/admin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/audit"
+	"github.com/xixipi-lining/iceberg-rest-catalog/commit"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+	"github.com/xixipi-lining/iceberg-rest-catalog/validate"
+)
+
+// problems returns the messages of a validation error by path.
+func problems(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var errs validate.Errors
+	require.True(t, errors.As(err, &errs), "expected validate.Errors, got %v", err)
+	byPath := make(map[string]string, len(errs))
+	for _, p := range errs {
+		byPath[p.Path] = p.Message
+	}
+	return byPath
+}
+
+func testFlags() *flag.FlagSet {
+	return flag.NewFlagSet("test", flag.ContinueOnError)
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), cfgFile)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestConfigSource(t *testing.T) {
+	const file = `
+default-catalog: from-file
+catalog:
+  from-file:
+    type: sql
+port: 8081
+host: 10.0.0.1
+log:
+  debug: true
+`
+
+	t.Run("File", func(t *testing.T) {
+		source, err := parseFlags(testFlags(), []string{"-config", writeConfig(t, file)})
+		require.NoError(t, err)
+		cfg, err := source.load()
+		require.NoError(t, err)
+
+		assert.Equal(t, "from-file", cfg.DefaultCatalog)
+		assert.Equal(t, 8081, cfg.Port)
+		assert.Equal(t, "10.0.0.1", cfg.Host)
+		assert.True(t, cfg.LogConfig.Debug)
+		assert.Equal(t, defaultConfig().HTTP, cfg.HTTP, "keys missing from the file keep their defaults")
+	})
+
+	t.Run("EnvOverridesFile", func(t *testing.T) {
+		t.Setenv("ICEBERG_REST_PORT", "8082")
+		t.Setenv("ICEBERG_REST_CATALOG_FROM__FILE_URI", "file:env.db")
+
+		source, err := parseFlags(testFlags(), []string{"-config", writeConfig(t, file)})
+		require.NoError(t, err)
+		cfg, err := source.load()
+		require.NoError(t, err)
+
+		assert.Equal(t, 8082, cfg.Port)
+		assert.Equal(t, "10.0.0.1", cfg.Host)
+		assert.Equal(t, "sql", cfg.Catalogs["from-file"]["type"])
+		assert.Equal(t, "file:env.db", cfg.Catalogs["from-file"]["uri"])
+	})
+
+	t.Run("FlagsOverrideEnv", func(t *testing.T) {
+		t.Setenv("ICEBERG_REST_PORT", "8082")
+		t.Setenv("ICEBERG_REST_HOST", "10.0.0.2")
+
+		source, err := parseFlags(testFlags(), []string{"-config", writeConfig(t, file), "-port", "8083", "-default-catalog", "from-flag"})
+		require.NoError(t, err)
+		cfg, err := source.load()
+		require.NoError(t, err)
+
+		assert.Equal(t, 8083, cfg.Port)
+		assert.Equal(t, "10.0.0.2", cfg.Host, "flags that aren't given don't override")
+		assert.Equal(t, "from-flag", cfg.DefaultCatalog)
+
+		// Reloads apply the flags again.
+		t.Setenv("ICEBERG_REST_PORT", "8084")
+		cfg, err = source.load()
+		require.NoError(t, err)
+		assert.Equal(t, 8083, cfg.Port)
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		source, err := parseFlags(testFlags(), []string{"-config", filepath.Join(t.TempDir(), "missing.yaml")})
+		require.NoError(t, err)
+		_, err = source.load()
+		assert.ErrorIs(t, err, fs.ErrNotExist, "a file given with -config must exist")
+	})
+
+	t.Run("MissingDefaultFile", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("GOICEBERG_HOME", home)
+
+		source, err := parseFlags(testFlags(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(home, cfgFile), source.path)
+		assert.True(t, source.unconfigured())
+
+		cfg, err := source.load()
+		require.NoError(t, err)
+		assert.Equal(t, "sql", cfg.Catalogs[cfg.DefaultCatalog]["type"], "nothing configured serves the local catalog")
+		assert.NoError(t, cfg.Validate())
+
+		_, err = source.reload()
+		assert.ErrorIs(t, err, fs.ErrNotExist, "reloads require the file")
+	})
+
+	t.Run("NoCatalog", func(t *testing.T) {
+		t.Setenv("GOICEBERG_HOME", t.TempDir())
+
+		for name, setup := range map[string]func(t *testing.T) []string{
+			"File": func(t *testing.T) []string {
+				return []string{"-config", writeConfig(t, "port: 8081\n")}
+			},
+			"Env": func(t *testing.T) []string {
+				t.Setenv("ICEBERG_REST_PORT", "8081")
+				return nil
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				source, err := parseFlags(testFlags(), setup(t))
+				require.NoError(t, err)
+				assert.False(t, source.unconfigured())
+
+				cfg, err := source.load()
+				require.NoError(t, err)
+				assert.Empty(t, cfg.Catalogs, "a configured server doesn't fall back to the local catalog")
+				assert.Contains(t, problems(t, cfg.Validate()), "default-catalog")
+			})
+		}
+	})
+
+	t.Run("UnexpectedArguments", func(t *testing.T) {
+		_, err := parseFlags(testFlags(), []string{"serve"})
+		assert.ErrorContains(t, err, "unexpected arguments")
+	})
+}
+
+// validConfig is a config serving a SQLite catalog that passes validation.
+func validConfig() *Config {
+	cfg := defaultConfig()
+	cfg.Catalogs = map[string]iceberg.Properties{
+		cfg.DefaultCatalog: {"type": "sql", "sql.driver": "sqlite3", "sql.dialect": "sqlite"},
+	}
+	return cfg
+}
+
+func TestConfigValidate(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		assert.NoError(t, validConfig().Validate())
+	})
+
+	t.Run("Problems", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.Catalogs["other"] = iceberg.Properties{"type": "sql", "sql.driver": "sqlite3", "sql.dialect": "sqlite", "uri": "${env:"}
+		cfg.ReadOnly.Catalogs = []string{"missing"}
+		cfg.Port = 0
+		cfg.Admin = admin.Config{Enabled: true, Port: 9090}
+		cfg.AuditConfig.Sink = "kafka"
+		cfg.Tracing.SampleRatio = 2
+		cfg.Commit = commit.Config{Enabled: true}
+		cfg.HTTP.IdleTimeout = -time.Second
+		cfg.Reload.Interval = 0
+
+		got := problems(t, cfg.Validate())
+		assert.Contains(t, got, "catalog.other.uri")
+		assert.Equal(t, `catalog "missing" is not defined under catalog`, got["read-only.catalogs[0]"])
+		assert.Contains(t, got["port"], "out of range")
+		assert.Equal(t, "is required when the admin listener is enabled", got["admin.token"])
+		assert.Contains(t, got["audit.sink"], `unknown audit sink "kafka"`)
+		assert.Equal(t, "must be between 0 and 1", got["tracing.sample-ratio"])
+		assert.Equal(t, "must be at least 1", got["commit-retry.max-attempts"])
+		assert.Equal(t, "must not be negative", got["http.idle-timeout"])
+		assert.Equal(t, "must be positive when watch is enabled", got["reload.interval"])
+		assert.NotContains(t, got, "default-catalog")
+	})
+
+	t.Run("SkipFilesystem", func(t *testing.T) {
+		blocker := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(blocker, nil, 0o600))
+
+		cfg := validConfig()
+		cfg.LogConfig.Outputs = []logger.Output{{Type: logger.OutputFile, FileName: filepath.Join(blocker, "app.log")}}
+		cfg.AuditConfig = audit.Config{Sink: "jsonl", Path: filepath.Join(blocker, "audit.jsonl")}
+
+		got := problems(t, cfg.Validate())
+		assert.Contains(t, got["log.outputs[0].file-name"], "is not a directory")
+		assert.Contains(t, got["audit.path"], "is not a directory")
+
+		assert.NoError(t, cfg.Validate(validate.SkipFilesystem()), "paths are checked where they are written")
+
+		cfg.AuditConfig.Path = ""
+		got = problems(t, cfg.Validate(validate.SkipFilesystem()))
+		assert.Equal(t, "is required", got["audit.path"], "files still have to be named")
+	})
+}
+
+func TestValidateConfigCommand(t *testing.T) {
+	t.Setenv("GOICEBERG_HOME", t.TempDir())
+	blocker := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(blocker, nil, 0o600))
+
+	valid := writeConfig(t, `
+catalog:
+  default:
+    type: sql
+    sql.driver: sqlite3
+    sql.dialect: sqlite
+`)
+	unwritable := writeConfig(t, `
+catalog:
+  default:
+    type: sql
+    sql.driver: sqlite3
+    sql.dialect: sqlite
+log:
+  outputs:
+    - type: file
+      file-name: `+filepath.Join(blocker, "app.log")+`
+`)
+
+	for _, tc := range []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{name: "Valid", args: []string{"-config", valid}, stdout: "config is valid"},
+		{name: "Invalid", args: []string{"-config", valid, "-port", "70000"}, code: 1, stderr: "port: 70000 is out of range"},
+		{name: "Unwritable", args: []string{"-config", unwritable}, code: 1, stderr: "log.outputs[0].file-name:"},
+		{name: "SkipFilesystem", args: []string{"-config", unwritable, "-skip-filesystem"}, stdout: "config is valid"},
+		{name: "MissingFile", args: []string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}, code: 1, stderr: "failed to load config"},
+		{name: "UnknownFlag", args: []string{"-unknown"}, code: 2, stderr: "flag provided but not defined"},
+		{name: "Unconfigured", stdout: "config is valid", stderr: "would serve a local SQLite catalog"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			assert.Equal(t, tc.code, validateConfig(tc.args, &stdout, &stderr), stderr.String())
+			assert.Contains(t, stdout.String(), tc.stdout)
+			assert.Contains(t, stderr.String(), tc.stderr)
+		})
+	}
+}
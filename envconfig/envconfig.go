@@ -0,0 +1,192 @@
+// Package envconfig overrides config fields from environment variables named
+// after their YAML paths, for deployments that configure through the
+// environment rather than a file.
+package envconfig
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Apply sets fields of the struct v points to from environ, a list of
+// KEY=VALUE pairs as returned by os.Environ.
+//
+// The variable of a field is prefix followed by the YAML keys of its path,
+// uppercased, with "-" spelled "_", and joined by "_": log.max-size is
+// PREFIX_LOG_MAX_SIZE. Values are parsed as YAML, so a list or a whole section
+// can be given in flow style, e.g. [a, b] or {enabled: true}.
+//
+// Entries of maps with string keys can also be set one by one. Map keys often
+// hold dots and dashes, so, as in the Iceberg REST fixture, "_" stands for "."
+// and "__" for "-" in them, and they are lowercased:
+// PREFIX_CATALOG_DEFAULT_S3_SECRET__ACCESS__KEY sets s3.secret-access-key of
+// catalog default. A map of maps, such as the catalogs, is keyed by the name up
+// to the first "_" unless it already holds a longer matching name. A variable
+// naming one of its entries as a whole, e.g. PREFIX_CATALOG_DEFAULT, is parsed
+// as YAML and merged into the entry, which is how keys holding "_" are set.
+//
+// Every variable that fails to parse is reported.
+func Apply(prefix string, environ []string, v any) error {
+	vars := make(map[string]string)
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(name, prefix+"_") {
+			vars[name] = value
+		}
+	}
+
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("envconfig: expected a pointer to a struct, got %T", v)
+	}
+	var errs []error
+	applyStruct(target.Elem(), prefix, vars, &errs)
+	return errors.Join(errs...)
+}
+
+func applyStruct(v reflect.Value, name string, vars map[string]string, errs *[]error) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+		applyValue(v.Field(i), name+"_"+fieldName(key), vars, errs)
+	}
+}
+
+func applyValue(v reflect.Value, name string, vars map[string]string, errs *[]error) {
+	if value, ok := vars[name]; ok {
+		if err := set(v, value); err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		applyStruct(v, name, vars, errs)
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			applyMap(v, name, vars, errs)
+		}
+	}
+}
+
+// applyMap sets the entries of a map of strings, or of a map of maps of
+// strings, from the variables under name.
+func applyMap(v reflect.Value, name string, vars map[string]string, errs *[]error) {
+	elem := v.Type().Elem()
+	nested := elem.Kind() == reflect.Map && elem.Key().Kind() == reflect.String && elem.Elem().Kind() == reflect.String
+	if elem.Kind() != reflect.String && !nested {
+		return
+	}
+
+	// Sorted so that a whole entry is merged before the keys set one by one,
+	// and the result doesn't depend on map order.
+	names := make([]string, 0, len(vars))
+	for n := range vars {
+		if strings.HasPrefix(n, name+"_") {
+			names = append(names, n)
+		}
+	}
+	slices.Sort(names)
+
+	for _, n := range names {
+		rest := strings.TrimPrefix(n, name+"_")
+		if rest == "" {
+			continue
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		if !nested {
+			v.SetMapIndex(reflect.ValueOf(mapKey(rest)).Convert(v.Type().Key()), reflect.ValueOf(vars[n]).Convert(elem))
+			continue
+		}
+
+		outer, inner := splitNested(v, rest)
+		outerKey := reflect.ValueOf(outer).Convert(v.Type().Key())
+		entries := reflect.New(elem).Elem()
+		if current := v.MapIndex(outerKey); current.IsValid() && !current.IsNil() {
+			entries.Set(current)
+		} else {
+			entries.Set(reflect.MakeMap(elem))
+		}
+		if inner == "" {
+			// The whole entry, merged into what is already set.
+			if err := set(entries, vars[n]); err != nil {
+				*errs = append(*errs, fmt.Errorf("%s: %w", n, err))
+				continue
+			}
+		} else {
+			entries.SetMapIndex(reflect.ValueOf(mapKey(inner)).Convert(elem.Key()), reflect.ValueOf(vars[n]).Convert(elem.Elem()))
+		}
+		v.SetMapIndex(outerKey, entries)
+	}
+}
+
+// splitNested splits rest into the key of the outer map and the encoded key
+// of the inner one, preferring the longest outer key already in v. The inner
+// key is empty if rest names a whole entry.
+func splitNested(v reflect.Value, rest string) (string, string) {
+	best := ""
+	for _, k := range v.MapKeys() {
+		key := k.String()
+		if rest == mapName(key) {
+			return key, ""
+		}
+		if strings.HasPrefix(rest, mapName(key)+"_") && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best != "" {
+		return best, strings.TrimPrefix(rest, mapName(best)+"_")
+	}
+
+	for i := 0; i < len(rest); i++ {
+		if rest[i] != '_' {
+			continue
+		}
+		if i+1 < len(rest) && rest[i+1] == '_' {
+			// A "-" within the name.
+			i++
+			continue
+		}
+		return mapKey(rest[:i]), rest[i+1:]
+	}
+	return mapKey(rest), ""
+}
+
+func set(v reflect.Value, value string) error {
+	if v.Kind() == reflect.String {
+		v.SetString(value)
+		return nil
+	}
+	return yaml.Unmarshal([]byte(value), v.Addr().Interface())
+}
+
+// fieldName spells a YAML key of a struct field in a variable name.
+func fieldName(key string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(key))
+}
+
+// mapName spells a map key in a variable name.
+func mapName(key string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "__", ".", "_").Replace(key))
+}
+
+// mapKey reverses mapName, up to case.
+func mapKey(name string) string {
+	return strings.NewReplacer("__", "-", "_", ".").Replace(strings.ToLower(name))
+}
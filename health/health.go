@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+type Config struct {
+	// Backend enables a catalog round trip (listing namespaces).
+	Backend bool `yaml:"backend"`
+	// Warehouse is a location the FileIO probe writes, reads and removes a
+	// small object under. Empty disables the probe.
+	Warehouse string `yaml:"warehouse"`
+	// Timeout bounds a single probe.
+	Timeout time.Duration `yaml:"timeout"`
+	// CacheTTL is how long a report is reused so frequent readiness polls
+	// don't translate into backend load.
+	CacheTTL time.Duration `yaml:"cache-ttl"`
+}
+
+// Probe checks a single dependency.
+type Probe interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type ComponentStatus struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status     string            `json:"status"`
+	CheckedAt  time.Time         `json:"checked-at"`
+	Components []ComponentStatus `json:"components"`
+}
+
+// Checker runs probes concurrently and caches the combined report.
+type Checker struct {
+	probes   []Probe
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu     sync.Mutex
+	report *Report
+}
+
+func NewChecker(timeout, cacheTTL time.Duration, probes ...Probe) *Checker {
+	return &Checker{probes: probes, timeout: timeout, cacheTTL: cacheTTL}
+}
+
+// Check returns the cached report if it is fresh and runs the probes
+// otherwise. Concurrent callers wait for a single run.
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.report != nil && time.Since(c.report.CheckedAt) < c.cacheTTL {
+		return c.report.clone()
+	}
+
+	// Detach from the caller: the report is shared with everyone waiting, so
+	// one client hanging up must not mark the components down.
+	report := c.run(context.WithoutCancel(ctx))
+	c.report = &report
+	return report.clone()
+}
+
+func (r *Report) clone() Report {
+	cloned := *r
+	cloned.Components = slices.Clone(r.Components)
+	return cloned
+}
+
+func (c *Checker) run(ctx context.Context) Report {
+	report := Report{
+		Status:     StatusUp,
+		CheckedAt:  time.Now(),
+		Components: make([]ComponentStatus, len(c.probes)),
+	}
+
+	var wg sync.WaitGroup
+	for i, probe := range c.probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			probeCtx := ctx
+			if c.timeout > 0 {
+				var cancel context.CancelFunc
+				probeCtx, cancel = context.WithTimeout(ctx, c.timeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			err := probe.Check(probeCtx)
+			status := ComponentStatus{
+				Name:    probe.Name(),
+				Status:  StatusUp,
+				Latency: time.Since(start).String(),
+			}
+			if err != nil {
+				status.Status = StatusDown
+				status.Error = err.Error()
+			}
+			report.Components[i] = status
+		}()
+	}
+	wg.Wait()
+
+	for _, component := range report.Components {
+		if component.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
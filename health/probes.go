@@ -0,0 +1,90 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apache/iceberg-go/catalog"
+	icebergio "github.com/apache/iceberg-go/io"
+	"github.com/google/uuid"
+)
+
+type backendProbe struct {
+	cat catalog.Catalog
+}
+
+// BackendProbe lists the top-level namespaces of cat.
+func BackendProbe(cat catalog.Catalog) Probe {
+	return &backendProbe{cat: cat}
+}
+
+func (p *backendProbe) Name() string {
+	return "backend"
+}
+
+func (p *backendProbe) Check(ctx context.Context) error {
+	_, err := p.cat.ListNamespaces(ctx, nil)
+	return err
+}
+
+type fileIOProbe struct {
+	location string
+	props    func() map[string]string
+}
+
+// FileIOProbe writes, reads back and removes a small object under location
+// using the FileIO configured by the properties props returns. They are read
+// on every check, so that the probe follows a catalog reopened with rotated
+// credentials.
+func FileIOProbe(location string, props func() map[string]string) Probe {
+	return &fileIOProbe{location: strings.TrimSuffix(location, "/"), props: props}
+}
+
+func (p *fileIOProbe) Name() string {
+	return "warehouse"
+}
+
+func (p *fileIOProbe) Check(ctx context.Context) error {
+	fs, err := icebergio.LoadFS(ctx, p.props(), p.location)
+	if err != nil {
+		return err
+	}
+	wfs, ok := fs.(icebergio.WriteFileIO)
+	if !ok {
+		return fmt.Errorf("file io for %s is read-only", p.location)
+	}
+
+	name := p.location + "/.readyz/" + uuid.New().String()
+	content := []byte("readyz")
+
+	w, err := wfs.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(content); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	defer fs.Remove(name)
+
+	f, err := fs.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, content) {
+		return fmt.Errorf("read back %d bytes from %s, expected %d", len(got), name, len(content))
+	}
+	return nil
+}
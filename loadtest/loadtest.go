@@ -0,0 +1,310 @@
+// Package loadtest drives a catalog server with a mix of create, load, commit
+// and list calls through the iceberg-go REST client and reports throughput,
+// latency percentiles and commit conflict rates.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+)
+
+// Operations of a load test.
+const (
+	OpCreate = "create"
+	OpLoad   = "load"
+	OpCommit = "commit"
+	OpList   = "list"
+)
+
+// Ops lists the operations in the order they are reported.
+var Ops = []string{OpCreate, OpLoad, OpCommit, OpList}
+
+// Mix weighs how often each operation is picked.
+type Mix map[string]int
+
+// Predefined mixes. "read-heavy" resembles query engines planning scans,
+// "write-heavy" streaming ingestion into a handful of tables.
+var Mixes = map[string]Mix{
+	"read-heavy":  {OpCreate: 1, OpLoad: 80, OpCommit: 4, OpList: 15},
+	"write-heavy": {OpCreate: 1, OpLoad: 30, OpCommit: 65, OpList: 4},
+	"balanced":    {OpCreate: 5, OpLoad: 50, OpCommit: 30, OpList: 15},
+}
+
+// ParseMix reads a predefined mix name or weights such as
+// "load=8,commit=2".
+func ParseMix(s string) (Mix, error) {
+	if mix, ok := Mixes[s]; ok {
+		return mix, nil
+	}
+
+	mix := make(Mix)
+	for _, part := range strings.Split(s, ",") {
+		op, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("loadtest: invalid mix entry %q, expected op=weight", part)
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("loadtest: invalid weight for %s: %q", op, weight)
+		}
+		mix[op] = w
+	}
+	return mix, mix.validate()
+}
+
+func (m Mix) validate() error {
+	total := 0
+	for op, w := range m {
+		if !slices.Contains(Ops, op) {
+			return fmt.Errorf("loadtest: unknown operation %q", op)
+		}
+		total += w
+	}
+	if total == 0 {
+		return errors.New("loadtest: mix has no operations")
+	}
+	return nil
+}
+
+func (m Mix) String() string {
+	parts := make([]string, 0, len(m))
+	for _, op := range Ops {
+		if w := m[op]; w > 0 {
+			parts = append(parts, op+"="+strconv.Itoa(w))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+type Config struct {
+	// Namespace holds the tables of the run. It is created if missing.
+	Namespace string
+	// Tables is the number of tables loaded and committed to. Fewer tables
+	// mean more commit conflicts.
+	Tables int
+	// Concurrency is the number of clients issuing calls in parallel.
+	Concurrency int
+	// Duration bounds the run, Operations the number of calls. The run stops
+	// at whichever comes first; zero means no bound.
+	Duration   time.Duration
+	Operations int
+	Mix        Mix
+	// Seed makes the sequence of operations reproducible.
+	Seed uint64
+	// Timer, if set, is started right before the first operation and
+	// stopped right after the last one returns, so that a benchmark passing
+	// its testing.B times the operations alone.
+	Timer Timer
+}
+
+// Timer is implemented by testing.B.
+type Timer interface {
+	StartTimer()
+	StopTimer()
+}
+
+var schema = iceberg.NewSchema(0,
+	iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64, Required: true},
+	iceberg.NestedField{ID: 2, Name: "payload", Type: iceberg.PrimitiveTypes.String},
+)
+
+var arrowSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "payload", Type: arrow.BinaryTypes.String, Nullable: true},
+}, nil)
+
+// Run drives cat according to cfg and reports the results. It first creates
+// the namespace and the tables, which isn't measured.
+func Run(ctx context.Context, cat *rest.Catalog, cfg Config) (*Report, error) {
+	if err := cfg.Mix.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Duration <= 0 && cfg.Operations <= 0 {
+		return nil, errors.New("loadtest: either a duration or a number of operations is required")
+	}
+	cfg.Tables = max(cfg.Tables, 1)
+	cfg.Concurrency = max(cfg.Concurrency, 1)
+
+	ns := table.Identifier{cfg.Namespace}
+	if err := cat.CreateNamespace(ctx, ns, nil); err != nil && !errors.Is(err, catalog.ErrNamespaceAlreadyExists) {
+		return nil, fmt.Errorf("loadtest: creating namespace: %w", err)
+	}
+	tables := make([]table.Identifier, cfg.Tables)
+	for i := range tables {
+		tables[i] = table.Identifier{cfg.Namespace, fmt.Sprintf("table_%d", i)}
+		// Checked first, not every backend reports an existing table as
+		// ErrTableAlreadyExists.
+		exists, err := cat.CheckTableExists(ctx, tables[i])
+		if err != nil {
+			return nil, fmt.Errorf("loadtest: checking %s: %w", strings.Join(tables[i], "."), err)
+		}
+		if exists {
+			continue
+		}
+		if _, err := cat.CreateTable(ctx, tables[i], schema); err != nil {
+			return nil, fmt.Errorf("loadtest: creating %s: %w", strings.Join(tables[i], "."), err)
+		}
+	}
+
+	runCtx := ctx
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	// Each worker owns its samples, they are merged once the run is over.
+	var (
+		issued  atomic.Int64
+		wg      sync.WaitGroup
+		workers = make([]*worker, cfg.Concurrency)
+		// Created tables get a per-run prefix so that reruns against the
+		// same server don't collide.
+		prefix = strconv.FormatInt(time.Now().UnixNano(), 36)
+	)
+	if cfg.Timer != nil {
+		cfg.Timer.StartTimer()
+	}
+	start := time.Now()
+	for i := range workers {
+		w := &worker{
+			id:      i,
+			cat:     cat,
+			cfg:     cfg,
+			tables:  tables,
+			prefix:  prefix,
+			rng:     rand.New(rand.NewPCG(cfg.Seed, uint64(i))),
+			samples: newSamples(),
+		}
+		workers[i] = w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for runCtx.Err() == nil {
+				if cfg.Operations > 0 && issued.Add(1) > int64(cfg.Operations) {
+					return
+				}
+				w.step(runCtx)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	if cfg.Timer != nil {
+		cfg.Timer.StopTimer()
+	}
+
+	merged := newSamples()
+	for _, w := range workers {
+		merged.merge(w.samples)
+	}
+	return newReport(cfg, elapsed, merged), nil
+}
+
+type worker struct {
+	id      int
+	cat     *rest.Catalog
+	cfg     Config
+	tables  []table.Identifier
+	prefix  string
+	rng     *rand.Rand
+	samples *samples
+	created int
+}
+
+func (w *worker) pick() string {
+	total := 0
+	for _, op := range Ops {
+		total += w.cfg.Mix[op]
+	}
+	n := w.rng.IntN(total)
+	for _, op := range Ops {
+		if n < w.cfg.Mix[op] {
+			return op
+		}
+		n -= w.cfg.Mix[op]
+	}
+	return OpLoad
+}
+
+func (w *worker) step(ctx context.Context) {
+	op := w.pick()
+	start := time.Now()
+	err := w.do(ctx, op)
+	elapsed := time.Since(start)
+	if ctx.Err() != nil {
+		// Cut off by the end of the run, not a result.
+		return
+	}
+	w.samples.record(op, elapsed, err)
+}
+
+func (w *worker) do(ctx context.Context, op string) error {
+	switch op {
+	case OpCreate:
+		w.created++
+		ident := table.Identifier{w.cfg.Namespace, fmt.Sprintf("created_%s_%d_%d", w.prefix, w.id, w.created)}
+		_, err := w.cat.CreateTable(ctx, ident, schema)
+		return err
+	case OpLoad:
+		_, err := w.cat.LoadTable(ctx, w.randomTable(), nil)
+		return err
+	case OpCommit:
+		return w.commit(ctx)
+	default:
+		for _, err := range w.cat.ListTables(ctx, table.Identifier{w.cfg.Namespace}) {
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (w *worker) randomTable() table.Identifier {
+	return w.tables[w.rng.IntN(len(w.tables))]
+}
+
+// commit appends a few rows the way a writer job does: load the table, write
+// a data file and commit a new snapshot asserting the branch head it saw.
+func (w *worker) commit(ctx context.Context) error {
+	tbl, err := w.cat.LoadTable(ctx, w.randomTable(), nil)
+	if err != nil {
+		return err
+	}
+
+	b := array.NewRecordBuilder(memory.DefaultAllocator, arrowSchema)
+	defer b.Release()
+	for i := range 10 {
+		b.Field(0).(*array.Int64Builder).Append(w.rng.Int64())
+		b.Field(1).(*array.StringBuilder).Append("row-" + strconv.Itoa(i))
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+	rows := array.NewTableFromRecords(arrowSchema, []arrow.Record{rec})
+	defer rows.Release()
+
+	_, err = tbl.AppendTable(ctx, rows, 10, nil)
+	return err
+}
+
+// isConflict reports whether a commit lost against a concurrent one.
+func isConflict(err error) bool {
+	return errors.Is(err, rest.ErrCommitFailed)
+}
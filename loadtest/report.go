@@ -0,0 +1,169 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type samples struct {
+	latencies map[string][]time.Duration
+	errors    map[string]int
+	conflicts map[string]int
+}
+
+func newSamples() *samples {
+	return &samples{
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]int),
+		conflicts: make(map[string]int),
+	}
+}
+
+func (s *samples) record(op string, d time.Duration, err error) {
+	s.latencies[op] = append(s.latencies[op], d)
+	switch {
+	case err == nil:
+	case isConflict(err):
+		s.conflicts[op]++
+	default:
+		s.errors[op]++
+	}
+}
+
+func (s *samples) merge(other *samples) {
+	for op, l := range other.latencies {
+		s.latencies[op] = append(s.latencies[op], l...)
+	}
+	for op, n := range other.errors {
+		s.errors[op] += n
+	}
+	for op, n := range other.conflicts {
+		s.conflicts[op] += n
+	}
+}
+
+// OpReport summarizes the calls of one operation. Latencies include failed
+// calls.
+type OpReport struct {
+	Operation string        `json:"operation"`
+	Count     int           `json:"count"`
+	Errors    int           `json:"errors"`
+	Conflicts int           `json:"conflicts"`
+	PerSecond float64       `json:"per-second"`
+	Mean      time.Duration `json:"mean-ns"`
+	P50       time.Duration `json:"p50-ns"`
+	P99       time.Duration `json:"p99-ns"`
+}
+
+// ConflictRate is the share of calls that failed with a commit conflict.
+func (r OpReport) ConflictRate() float64 {
+	if r.Count == 0 {
+		return 0
+	}
+	return float64(r.Conflicts) / float64(r.Count)
+}
+
+type Report struct {
+	Mix         string        `json:"mix"`
+	Tables      int           `json:"tables"`
+	Concurrency int           `json:"concurrency"`
+	Elapsed     time.Duration `json:"elapsed-ns"`
+	Total       OpReport      `json:"total"`
+	Ops         []OpReport    `json:"operations"`
+}
+
+func newReport(cfg Config, elapsed time.Duration, s *samples) *Report {
+	r := &Report{
+		Mix:         cfg.Mix.String(),
+		Tables:      cfg.Tables,
+		Concurrency: cfg.Concurrency,
+		Elapsed:     elapsed,
+	}
+
+	var all []time.Duration
+	for _, op := range Ops {
+		latencies := s.latencies[op]
+		if len(latencies) == 0 {
+			continue
+		}
+		all = append(all, latencies...)
+		op := summarize(op, latencies, elapsed)
+		op.Errors = s.errors[op.Operation]
+		op.Conflicts = s.conflicts[op.Operation]
+		r.Ops = append(r.Ops, op)
+		r.Total.Errors += op.Errors
+		r.Total.Conflicts += op.Conflicts
+	}
+	total := summarize("total", all, elapsed)
+	total.Errors, total.Conflicts = r.Total.Errors, r.Total.Conflicts
+	r.Total = total
+	return r
+}
+
+func summarize(op string, latencies []time.Duration, elapsed time.Duration) OpReport {
+	r := OpReport{Operation: op, Count: len(latencies)}
+	if len(latencies) == 0 {
+		return r
+	}
+	slices.Sort(latencies)
+
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+	r.Mean = sum / time.Duration(len(latencies))
+	r.P50 = percentile(latencies, 0.50)
+	r.P99 = percentile(latencies, 0.99)
+	if elapsed > 0 {
+		r.PerSecond = float64(len(latencies)) / elapsed.Seconds()
+	}
+	return r
+}
+
+// percentile returns the nearest-rank percentile p of sorted.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// WriteText writes a table for people to read.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "mix %s, %d tables, %d clients, %s\n", r.Mix, r.Tables, r.Concurrency, r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintln(tw, "operation\tcount\tops/s\tp50\tp99\terrors\tconflicts\t")
+	for _, op := range append(slices.Clone(r.Ops), r.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%s\t%s\t%d\t%.1f%%\t\n",
+			op.Operation, op.Count, op.PerSecond,
+			op.P50.Round(time.Microsecond), op.P99.Round(time.Microsecond),
+			op.Errors, 100*op.ConflictRate())
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as a single JSON document.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteBench writes the report in the Go benchmark format, one line per
+// operation, so that runs on different commits can be compared with
+// benchstat. name identifies the scenario, e.g. the mix.
+func (r *Report) WriteBench(w io.Writer, name string) error {
+	name = strings.ReplaceAll(name, " ", "_")
+	for _, op := range append(slices.Clone(r.Ops), r.Total) {
+		_, err := fmt.Fprintf(w, "BenchmarkLoad/%s/op=%s\t%d\t%d ns/op\t%.2f ops/s\t%d p50-ns\t%d p99-ns\t%.4f conflicts/op\n",
+			name, op.Operation, op.Count, op.Mean.Nanoseconds(), op.PerSecond,
+			op.P50.Nanoseconds(), op.P99.Nanoseconds(), op.ConflictRate())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
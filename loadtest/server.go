@@ -0,0 +1,88 @@
+package loadtest
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	_ "github.com/apache/iceberg-go/catalog/sql"
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/server"
+	"github.com/xixipi-lining/iceberg-rest-catalog/cache"
+	"github.com/xixipi-lining/iceberg-rest-catalog/coalesce"
+	"github.com/xixipi-lining/iceberg-rest-catalog/commit"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+	"github.com/xixipi-lining/iceberg-rest-catalog/redact"
+)
+
+// ServerConfig selects the server features under test. The zero value is the
+// plain commit and load path.
+type ServerConfig struct {
+	Cache       cache.Config
+	Coalesce    coalesce.Config
+	CommitRetry commit.Config
+	CommitQueue commit.QueueConfig
+}
+
+// OpenCatalog opens a SQLite catalog with its database and warehouse kept in
+// dir. The database is a file rather than in memory, so that every connection
+// of the pool sees the same tables.
+func OpenCatalog(dir string) (catalog.Catalog, error) {
+	return catalog.Load(context.Background(), "loadtest", iceberg.Properties{
+		"type":                "sql",
+		"uri":                 "file:" + filepath.Join(dir, "catalog.db"),
+		"sql.driver":          "sqlite3",
+		"sql.dialect":         "sqlite",
+		"init_catalog_tables": "true",
+		"warehouse":           "file://" + filepath.Join(dir, "warehouse"),
+	})
+}
+
+// StartServer serves the catalog API in-process on a SQLite catalog and a
+// local warehouse, both kept in dir. Requests pass the middleware the server
+// runs with by default, and are logged to dir/server.log. The caller closes
+// the server.
+func StartServer(dir string, cfg ServerConfig) (*httptest.Server, error) {
+	backend, err := OpenCatalog(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cat := backend
+	opts := []handlers.Option{
+		handlers.WithCommitRetry(cfg.CommitRetry),
+		handlers.WithCommitQueue(cfg.CommitQueue),
+	}
+	if cfg.Coalesce.Enabled {
+		cat = coalesce.New(cat, cfg.Coalesce)
+	}
+	if cfg.Cache.Enabled {
+		cached := cache.New(cat, cfg.Cache)
+		cat = cached
+		opts = append(opts, handlers.WithMetadataEncoder(cached))
+	}
+
+	redactor, err := redact.New(redact.Config{})
+	if err != nil {
+		return nil, err
+	}
+	log := logger.NewLogger(&logger.Config{
+		Outputs: []logger.Output{{Type: logger.OutputFile, FileName: filepath.Join(dir, "server.log")}},
+	}, logger.WithOutputFilter(redactor.Writer))
+
+	gin.SetMode(gin.ReleaseMode)
+	engineCfg := server.DefaultEngineConfig()
+	engineCfg.Catalog = "loadtest"
+	engine, err := server.NewEngine(engineCfg, log, log, redactor, &server.Drainer{}, middleware.NewReadOnly(middleware.ReadOnlyConfig{}))
+	if err != nil {
+		return nil, err
+	}
+	router.Setup(engine, handlers.NewCatalogHandler(cat, handlers.Config{}, opts...))
+	return httptest.NewServer(engine), nil
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// levelSnapshot is replaced as a whole on every change so that the hot path
+// only needs an atomic load.
+type levelSnapshot struct {
+	global     zerolog.Level
+	components map[string]zerolog.Level
+}
+
+var (
+	levelsMu sync.Mutex
+	levels   atomic.Pointer[levelSnapshot]
+)
+
+func init() {
+	levels.Store(&levelSnapshot{global: zerolog.InfoLevel, components: map[string]zerolog.Level{}})
+}
+
+func enabled(component string, level zerolog.Level) bool {
+	snapshot := levels.Load()
+	if min, ok := snapshot.components[component]; ok {
+		return level >= min
+	}
+	return level >= snapshot.global
+}
+
+func updateLevels(update func(*levelSnapshot)) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+
+	current := levels.Load()
+	next := &levelSnapshot{global: current.global, components: maps.Clone(current.components)}
+	update(next)
+	levels.Store(next)
+}
+
+// namedLevels are the levels SetLevel accepts, from the most verbose.
+var namedLevels = []zerolog.Level{
+	zerolog.TraceLevel,
+	zerolog.DebugLevel,
+	zerolog.InfoLevel,
+	zerolog.WarnLevel,
+	zerolog.ErrorLevel,
+	zerolog.FatalLevel,
+	zerolog.PanicLevel,
+	zerolog.Disabled,
+}
+
+// parseLevel parses a level name. Unlike zerolog.ParseLevel it rejects the
+// empty string and numbers, which would set levels that silence every log.
+func parseLevel(level string) (zerolog.Level, error) {
+	names := make([]string, len(namedLevels))
+	for i, lvl := range namedLevels {
+		if strings.EqualFold(level, lvl.String()) {
+			return lvl, nil
+		}
+		names[i] = lvl.String()
+	}
+	return zerolog.NoLevel, fmt.Errorf("unknown log level %q, must be one of %s", level, strings.Join(names, ", "))
+}
+
+// SetLevel changes the minimum level at runtime. An empty component sets the
+// global level; otherwise the level overrides the global one for loggers
+// created with Named(component). The level must be a name from trace to
+// disabled.
+func SetLevel(component, level string) error {
+	lvl, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	updateLevels(func(s *levelSnapshot) {
+		if component == "" {
+			s.global = lvl
+		} else {
+			s.components[component] = lvl
+		}
+	})
+	return nil
+}
+
+// ResetLevel drops the override for component so it follows the global level
+// again.
+func ResetLevel(component string) {
+	updateLevels(func(s *levelSnapshot) {
+		delete(s.components, component)
+	})
+}
+
+// Levels returns the global level and the per-component overrides.
+func Levels() (string, map[string]string) {
+	snapshot := levels.Load()
+	components := make(map[string]string, len(snapshot.components))
+	for component, lvl := range snapshot.components {
+		components[component] = lvl.String()
+	}
+	return snapshot.global.String(), components
+}
@@ -21,6 +21,10 @@ type Logger interface {
 
 	WithFields(fields ...Field) Logger
 	WithField(key string, value any) Logger
+
+	// Named returns a logger for a component whose level can be changed
+	// independently at runtime.
+	Named(component string) Logger
 }
 
 type Field struct {
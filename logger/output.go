@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xixipi-lining/iceberg-rest-catalog/validate"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputFile   = "file"
+
+	TimeFormatUnix    = "unix"
+	TimeFormatUnixMs  = "unix-ms"
+	TimeFormatRFC3339 = "rfc3339"
+)
+
+// Output is one log destination. Files are rotated by lumberjack.
+type Output struct {
+	// Type is "stdout", "stderr" or "file".
+	Type string `yaml:"type"`
+	// Format overrides the logger's format for this output.
+	Format     string `yaml:"format"`
+	FileName   string `yaml:"file-name"`
+	MaxSize    int    `yaml:"max-size"`
+	MaxBackups int    `yaml:"max-backups"`
+	MaxAge     int    `yaml:"max-age"`
+	Compress   bool   `yaml:"compress"`
+}
+
+// Sampling thins out info, debug and trace logs. Within each Period the first
+// Burst messages per level are written, then every Thereafter-th one, or none
+// if Thereafter is 0. Warnings and errors are never sampled.
+type Sampling struct {
+	Enabled    bool          `yaml:"enabled"`
+	Burst      uint32        `yaml:"burst"`
+	Period     time.Duration `yaml:"period"`
+	Thereafter uint32        `yaml:"thereafter"`
+}
+
+// Validate reports settings NewLogger would otherwise silently replace by
+// defaults, and log files that can't be written.
+func (cfg *Config) Validate(opts ...validate.Option) error {
+	r := validate.NewReport(opts...)
+	checkFormat(&r, "format", cfg.Format)
+	switch cfg.TimeFormat {
+	case "", TimeFormatUnix, TimeFormatUnixMs, TimeFormatRFC3339:
+	default:
+		r.Add("time-format", "unknown log time format %q", cfg.TimeFormat)
+	}
+	if len(cfg.Outputs) == 0 && cfg.FileName != "" {
+		validate.Writable(&r, "file-name", cfg.FileName)
+	}
+	checkOutputs(&r, "outputs", cfg.Outputs)
+	checkOutputs(&r, "access-outputs", cfg.AccessOutputs)
+	if cfg.Sampling.Enabled && cfg.Sampling.Period <= 0 {
+		r.Add("sampling.period", "log sampling requires a positive period")
+	}
+	return r.Err()
+}
+
+func checkOutputs(r *validate.Report, path string, outputs []Output) {
+	for i, output := range outputs {
+		item := validate.Index(path, i)
+		checkFormat(r, validate.Join(item, "format"), output.Format)
+		switch output.Type {
+		case OutputStdout, OutputStderr:
+		case OutputFile:
+			validate.Writable(r, validate.Join(item, "file-name"), output.FileName)
+		default:
+			r.Add(validate.Join(item, "type"), "unknown log output type %q", output.Type)
+		}
+	}
+}
+
+func checkFormat(r *validate.Report, path, format string) {
+	switch format {
+	case "", FormatJSON, FormatConsole:
+	default:
+		r.Add(path, "unknown log format %q", format)
+	}
+}
+
+// outputs returns the configured destinations, falling back to the legacy
+// single file-name setting and then to stdout.
+func (cfg *Config) outputs() []Output {
+	if len(cfg.Outputs) > 0 {
+		return cfg.Outputs
+	}
+	if cfg.FileName != "" {
+		return []Output{{
+			Type:       OutputFile,
+			FileName:   cfg.FileName,
+			MaxSize:    cfg.MaxSize,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAge,
+			Compress:   cfg.Compress,
+		}}
+	}
+	return []Output{{Type: OutputStdout}}
+}
+
+// newWriter returns the writer for outputs along with the files it opens.
+func newWriter(cfg *Config, outputs []Output) (io.Writer, []io.Closer) {
+	writers := make([]io.Writer, 0, len(outputs))
+	var closers []io.Closer
+	for _, output := range outputs {
+		var w io.Writer
+		switch output.Type {
+		case OutputStderr:
+			w = os.Stderr
+		case OutputFile:
+			file := &lumberjack.Logger{
+				Filename:   output.FileName,
+				MaxSize:    output.MaxSize,
+				MaxBackups: output.MaxBackups,
+				MaxAge:     output.MaxAge,
+				Compress:   output.Compress,
+			}
+			w = file
+			closers = append(closers, file)
+		default:
+			w = os.Stdout
+		}
+
+		format := output.Format
+		if format == "" {
+			format = cfg.Format
+		}
+		if format == FormatConsole {
+			w = zerolog.ConsoleWriter{
+				Out:        w,
+				NoColor:    output.Type == OutputFile,
+				TimeFormat: time.RFC3339,
+			}
+		}
+		writers = append(writers, w)
+	}
+	return zerolog.MultiLevelWriter(writers...), closers
+}
+
+func timeFieldFormat(format string) string {
+	switch format {
+	case TimeFormatRFC3339:
+		return time.RFC3339Nano
+	case TimeFormatUnixMs:
+		return zerolog.TimeFormatUnixMs
+	default:
+		return zerolog.TimeFormatUnix
+	}
+}
+
+func (s Sampling) sampler() zerolog.Sampler {
+	if !s.Enabled {
+		return nil
+	}
+
+	// Each level gets its own budget so debug noise can't starve info logs.
+	return zerolog.LevelSampler{
+		TraceSampler: s.burst(),
+		DebugSampler: s.burst(),
+		InfoSampler:  s.burst(),
+	}
+}
+
+func (s Sampling) burst() zerolog.Sampler {
+	sampler := &zerolog.BurstSampler{Burst: s.Burst, Period: s.Period}
+	if s.Thereafter > 0 {
+		sampler.NextSampler = &zerolog.BasicSampler{N: s.Thereafter}
+	}
+	return sampler
+}
@@ -1,14 +1,28 @@
 package logger
 
 import (
-	"os"
+	"io"
+	"sync"
 
 	"github.com/rs/zerolog"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 type Config struct {
-	Debug      bool   `yaml:"debug"`
+	Debug bool `yaml:"debug"`
+	// Format is "json" (default) or "console" for human-readable lines.
+	Format string `yaml:"format"`
+	// TimeFormat is "unix" (default), "unix-ms" or "rfc3339".
+	TimeFormat string `yaml:"time-format"`
+
+	// Outputs are written to at once. Without any, logs go to FileName if set
+	// and to stdout otherwise.
+	Outputs []Output `yaml:"outputs"`
+	// AccessOutputs send request logs to their own destinations instead of
+	// the application outputs.
+	AccessOutputs []Output `yaml:"access-outputs"`
+
+	Sampling Sampling `yaml:"sampling"`
+
 	FileName   string `yaml:"file-name"`
 	MaxSize    int    `yaml:"max-size"`
 	MaxBackups int    `yaml:"max-backups"`
@@ -16,71 +30,163 @@ type Config struct {
 	Compress   bool   `yaml:"compress"`
 }
 
-func NewLogger(cfg *Config) Logger {
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	if cfg.Debug {
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+type Option func(*options)
+
+type options struct {
+	filter func(io.Writer) io.Writer
+}
+
+// WithOutputFilter wraps the log output, e.g. to scrub secrets from every
+// line before it is written.
+func WithOutputFilter(filter func(io.Writer) io.Writer) Option {
+	return func(o *options) {
+		o.filter = filter
 	}
+}
+
+func NewLogger(cfg *Config, opts ...Option) Logger {
+	return newFromOutputs(cfg, false, opts...)
+}
 
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+// NewAccessLogger returns a logger writing to cfg.AccessOutputs, or nil if
+// request logs share the application outputs.
+func NewAccessLogger(cfg *Config, opts ...Option) Logger {
+	if len(cfg.AccessOutputs) == 0 {
+		return nil
+	}
+	return newFromOutputs(cfg, true, opts...)
+}
+
+func newFromOutputs(cfg *Config, access bool, opts ...Option) Logger {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// Filtering happens in the wrapper so that components can be more
+	// verbose than the global level.
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	setGlobalLevel(cfg)
+
+	zerolog.TimeFieldFormat = timeFieldFormat(cfg.TimeFormat)
 	// Set caller skip frame count to skip the wrapper layer
 	zerolog.CallerSkipFrameCount = 3
 
-	writer := zerolog.MultiLevelWriter(os.Stdout)
-	if cfg.FileName != "" {
-		lj := &lumberjack.Logger{
-			Filename:   cfg.FileName,
-			MaxSize:    cfg.MaxSize,
-			MaxBackups: cfg.MaxBackups,
-			MaxAge:     cfg.MaxAge,
-			Compress:   cfg.Compress,
-		}
-		writer = zerolog.MultiLevelWriter(lj)
+	out := &switchWriter{filter: o.filter, access: access}
+	out.swap(cfg)
+
+	l := zerolog.New(out).With().Timestamp().Caller().Logger()
+	if sampler := cfg.Sampling.sampler(); sampler != nil {
+		l = l.Sample(sampler)
+	}
+	return &zerologLogger{Logger: l, out: out}
+}
+
+func setGlobalLevel(cfg *Config) {
+	level := zerolog.InfoLevel
+	if cfg.Debug {
+		level = zerolog.DebugLevel
+	}
+	updateLevels(func(s *levelSnapshot) {
+		s.global = level
+	})
+}
+
+// Reload applies the level, formats and outputs of cfg to l and to every
+// logger derived from it. The time format and sampling are fixed when the
+// logger is created, and so is whether request logs have outputs of their own.
+func Reload(l Logger, cfg *Config) {
+	zl, ok := l.(*zerologLogger)
+	if !ok || zl.out == nil {
+		return
 	}
+	setGlobalLevel(cfg)
+	zl.out.swap(cfg)
+}
+
+// switchWriter is the output of a logger and of all loggers derived from it,
+// so that Reload can replace the destinations underneath them.
+type switchWriter struct {
+	filter func(io.Writer) io.Writer
+	access bool
 
-	return newLogger(zerolog.New(writer).With().Timestamp().Caller().Logger())
+	mu      sync.RWMutex
+	w       io.Writer
+	closers []io.Closer
+}
+
+func (s *switchWriter) swap(cfg *Config) {
+	outputs := cfg.outputs()
+	if s.access && len(cfg.AccessOutputs) > 0 {
+		outputs = cfg.AccessOutputs
+	}
+	w, closers := newWriter(cfg, outputs)
+	if s.filter != nil {
+		w = s.filter(w)
+	}
+
+	s.mu.Lock()
+	previous := s.closers
+	s.w, s.closers = w, closers
+	s.mu.Unlock()
+
+	// No write is in progress on the previous outputs once the lock was held.
+	for _, c := range previous {
+		_ = c.Close()
+	}
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.w.Write(p)
 }
 
 type zerologLogger struct {
 	zerolog.Logger
+	component string
+	out       *switchWriter
 }
 
-func newLogger(l zerolog.Logger) Logger {
-	return &zerologLogger{
-		Logger: l,
+// event returns nil, on which zerolog calls are no-ops, if the level is
+// filtered out for this logger's component.
+func (l *zerologLogger) event(level zerolog.Level) *zerolog.Event {
+	if !enabled(l.component, level) {
+		return nil
 	}
+	return l.Logger.WithLevel(level)
 }
 
 func (l *zerologLogger) Info(msg string, fields ...Field) {
-	l.Logger.Info().Fields(fieldsToMap(fields)).Msg(msg)
+	l.event(zerolog.InfoLevel).Fields(fieldsToMap(fields)).Msg(msg)
 }
 
 func (l *zerologLogger) Infof(format string, args ...any) {
-	l.Logger.Info().Msgf(format, args...)
+	l.event(zerolog.InfoLevel).Msgf(format, args...)
 }
 
 func (l *zerologLogger) Debug(msg string, fields ...Field) {
-	l.Logger.Debug().Fields(fieldsToMap(fields)).Msg(msg)
+	l.event(zerolog.DebugLevel).Fields(fieldsToMap(fields)).Msg(msg)
 }
 
 func (l *zerologLogger) Debugf(format string, args ...any) {
-	l.Logger.Debug().Msgf(format, args...)
+	l.event(zerolog.DebugLevel).Msgf(format, args...)
 }
 
 func (l *zerologLogger) Warn(msg string, fields ...Field) {
-	l.Logger.Warn().Fields(fieldsToMap(fields)).Msg(msg)
+	l.event(zerolog.WarnLevel).Fields(fieldsToMap(fields)).Msg(msg)
 }
 
 func (l *zerologLogger) Warnf(format string, args ...any) {
-	l.Logger.Warn().Msgf(format, args...)
+	l.event(zerolog.WarnLevel).Msgf(format, args...)
 }
 
 func (l *zerologLogger) Error(msg string, fields ...Field) {
-	l.Logger.Error().Fields(fieldsToMap(fields)).Msg(msg)
+	l.event(zerolog.ErrorLevel).Fields(fieldsToMap(fields)).Msg(msg)
 }
 
 func (l *zerologLogger) Errorf(format string, args ...any) {
-	l.Logger.Error().Msgf(format, args...)
+	l.event(zerolog.ErrorLevel).Msgf(format, args...)
 }
 
 func (l *zerologLogger) Fatal(msg string, fields ...Field) {
@@ -101,13 +207,25 @@ func (l *zerologLogger) Panicf(format string, args ...any) {
 
 func (l *zerologLogger) WithFields(fields ...Field) Logger {
 	return &zerologLogger{
-		Logger: l.Logger.With().Fields(fieldsToMap(fields)).Logger(),
+		Logger:    l.Logger.With().Fields(fieldsToMap(fields)).Logger(),
+		component: l.component,
+		out:       l.out,
 	}
 }
 
 func (l *zerologLogger) WithField(key string, value any) Logger {
 	return &zerologLogger{
-		Logger: l.Logger.With().Interface(key, value).Logger(),
+		Logger:    l.Logger.With().Interface(key, value).Logger(),
+		component: l.component,
+		out:       l.out,
+	}
+}
+
+func (l *zerologLogger) Named(component string) Logger {
+	return &zerologLogger{
+		Logger:    l.Logger.With().Str("component", component).Logger(),
+		component: component,
+		out:       l.out,
 	}
 }
 
@@ -2,138 +2,278 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
 	"fmt"
-	"net/http"
+	"maps"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"syscall"
+	"time"
 
-	"github.com/apache/iceberg-go"
 	"github.com/apache/iceberg-go/catalog"
 	_ "github.com/apache/iceberg-go/catalog/glue"
 	_ "github.com/apache/iceberg-go/catalog/rest"
 	_ "github.com/apache/iceberg-go/catalog/sql"
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/oklog/run"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/admin"
 	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
 	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
 	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/server"
+	"github.com/xixipi-lining/iceberg-rest-catalog/audit"
+	"github.com/xixipi-lining/iceberg-rest-catalog/cache"
+	"github.com/xixipi-lining/iceberg-rest-catalog/coalesce"
+	"github.com/xixipi-lining/iceberg-rest-catalog/health"
 	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
-	"gopkg.in/yaml.v3"
+	"github.com/xixipi-lining/iceberg-rest-catalog/metrics"
+	"github.com/xixipi-lining/iceberg-rest-catalog/redact"
+	"github.com/xixipi-lining/iceberg-rest-catalog/reload"
+	"github.com/xixipi-lining/iceberg-rest-catalog/tracing"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-const (
-	cfgFile = ".iceberg-go.yaml"
-)
-
-type Config struct {
-	DefaultCatalog string                        `yaml:"default-catalog"`
-	Catalogs       map[string]iceberg.Properties `yaml:"catalog"`
-
-	ServerConfig handlers.Config `yaml:"server"`
+// exit reports a problem that keeps the server from starting.
+func exit(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
 
-	LogConfig logger.Config `yaml:"log"`
-	Port      int           `yaml:"port"`
-	Host      string        `yaml:"host"`
+// adminToken returns the token of the admin routes. Without one configured, a
+// random token is generated for this run and printed once, outside the logs,
+// so that the read-only switch stays usable but never unauthenticated.
+func adminToken(cfg admin.Config) (string, error) {
+	if cfg.Token != "" {
+		return cfg.Token, nil
+	}
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	generated := hex.EncodeToString(token)
+	fmt.Fprintf(os.Stderr, "admin.token is not set, generated for this run: %s\n", generated)
+	return generated, nil
 }
 
-func loadConfig(configPath string) (*Config, error) {
-	var path string
-	if len(configPath) > 0 {
-		path = configPath
-	} else {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return nil, err
-		}
-		path = filepath.Join(homeDir, cfgFile)
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		os.Exit(validateConfig(os.Args[2:], os.Stdout, os.Stderr))
 	}
 
-	file, err := os.ReadFile(path)
+	source, err := parseFlags(flag.NewFlagSet("iceberg-rest-catalog", flag.ExitOnError), os.Args[1:])
 	if err != nil {
-		return nil, err
-	}
-
-	cfg := Config{
-		DefaultCatalog: "default",
-		LogConfig: logger.Config{
-			Debug:    true,
-			MaxSize:  100,
-			Compress: false,
-		},
-		ServerConfig: handlers.Config{
-			Defaults:  map[string]string{},
-			Overrides: map[string]string{},
-		},
-		Port: 8080,
-		Host: "127.0.0.1",
-	}
-	err = yaml.Unmarshal(file, &cfg)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	cfg, err := source.load()
 	if err != nil {
-		return nil, err
+		exit("failed to load config: %s", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		exit("invalid config:\n%s", err)
 	}
 
-	return &cfg, nil
-}
+	redactor, err := redact.New(cfg.Redact)
+	if err != nil {
+		exit("invalid config: %s", err)
+	}
+	redactor.AddSecrets(cfg.ServerConfig.Defaults)
+	redactor.AddSecrets(cfg.ServerConfig.Overrides)
+	adminToken, err := adminToken(cfg.Admin)
+	if err != nil {
+		exit("failed to generate admin token: %s", err)
+	}
+	redactor.AddSecrets(map[string]string{"token": adminToken})
 
-func fromConfigFiles() (*Config, error) {
-	dir := os.Getenv("GOICEBERG_HOME")
-	if dir != "" {
-		dir = filepath.Join(dir, cfgFile)
+	// The config keeps the references, only the catalog sees their values.
+	props, err := resolveCatalog(context.Background(), cfg.Catalogs[cfg.DefaultCatalog], redactor)
+	if err != nil {
+		exit("failed to resolve secrets of catalog %s:\n%s", cfg.DefaultCatalog, err)
 	}
 
-	return loadConfig(dir)
-}
+	// Loading may add to props, which reloads compare against.
+	cat, err := catalog.Load(context.Background(), cfg.DefaultCatalog, maps.Clone(props))
+	if err != nil {
+		exit("failed to load catalog %s: %s", cfg.DefaultCatalog, redactor.String(err.Error()))
+	}
 
-func main() {
-	cfg, err := fromConfigFiles()
+	// The stats collector talks to the backend directly so that its listing
+	// doesn't skew the per-operation latency metrics. Reloads swap the
+	// catalog underneath every layer.
+	backend := reload.NewCatalog(cat)
+	cat = backend
+	if cfg.Metrics.Enabled {
+		cat = metrics.InstrumentCatalog(cat)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), &cfg.Tracing)
 	if err != nil {
-		panic(err)
+		exit("failed to set up tracing: %s", err)
+	}
+	if cfg.Tracing.Enabled() {
+		cat = tracing.InstrumentCatalog(cat)
 	}
 
-	props, ok := cfg.Catalogs[cfg.DefaultCatalog]
-	if !ok {
-		panic(fmt.Sprintf("catalog %s not found", cfg.DefaultCatalog))
+	if cfg.Coalesce.Enabled {
+		cat = coalesce.New(cat, cfg.Coalesce)
 	}
 
-	cat, err := catalog.Load(context.Background(), cfg.DefaultCatalog, props)
+	// The cache is outermost so that only misses show up as backend calls.
+	var (
+		encoder handlers.MetadataEncoder
+		cached  *cache.Catalog
+	)
+	if cfg.Cache.Enabled {
+		cached = cache.New(cat, cfg.Cache)
+		cat = cached
+		encoder = cached
+	}
+
+	auditSink, err := audit.NewSink(&cfg.AuditConfig)
 	if err != nil {
-		panic(err)
+		exit("failed to open audit sink: %s", err)
+	}
+	if auditSink != nil {
+		defer auditSink.Close()
+	}
+
+	handler := handlers.NewCatalogHandler(cat, cfg.ServerConfig,
+		handlers.WithAuditSink(auditSink),
+		handlers.WithRedactor(redactor),
+		handlers.WithMetadataEncoder(encoder),
+		handlers.WithCommitRetry(cfg.Commit),
+		handlers.WithCommitQueue(cfg.Queue),
+	)
+
+	log := logger.NewLogger(&cfg.LogConfig, logger.WithOutputFilter(redactor.Writer))
+	accessLog := log
+	loggers := []logger.Logger{log}
+	if l := logger.NewAccessLogger(&cfg.LogConfig, logger.WithOutputFilter(redactor.Writer)); l != nil {
+		accessLog = l
+		loggers = append(loggers, l)
+	}
+	if source.unconfigured() {
+		log.Warnf("no config file at %s and no %s_* variables set: serving a local SQLite catalog from iceberg_catalog.db with its warehouse in ./warehouse", source.path, envPrefix)
 	}
 
-	handler := handlers.NewCatalogHandler(cat, cfg.ServerConfig)
+	drainer := &server.Drainer{}
+	readOnly := middleware.NewReadOnly(cfg.ReadOnly)
+	engine, err := server.NewEngine(cfg.engine(), log, accessLog, redactor, drainer, readOnly)
+	if err != nil {
+		exit("invalid config: %s", err)
+	}
 
-	log := logger.NewLogger(&cfg.LogConfig)
+	if cfg.Metrics.Enabled {
+		router.SetupMetrics(engine, cfg.Metrics.Path)
+	}
 
-	engine := gin.New()
-	engine.Use(middleware.Logger(log))
-	engine.Use(cors.Default())
-	engine.Use(gin.Recovery())
+	reloader := &configReloader{
+		source:   source,
+		backend:  backend,
+		cache:    cached,
+		handler:  handler,
+		readOnly: readOnly,
+		redactor: redactor,
+		log:      log.Named("reload"),
+		loggers:  loggers,
+	}
+	reloader.current.Store(cfg)
+	reloader.props.Store(&props)
 
-	router := router.Setup(engine, handler)
+	var probes []health.Probe
+	if cfg.Health.Backend {
+		probes = append(probes, health.BackendProbe(backend))
+	}
+	if cfg.Health.Warehouse != "" {
+		probes = append(probes, health.FileIOProbe(cfg.Health.Warehouse, reloader.catalogProps))
+	}
+	checker := health.NewChecker(cfg.Health.Timeout, cfg.Health.CacheTTL, probes...)
+	router.SetupHealth(engine, handlers.NewHealthHandler(checker, redactor))
+	router.Setup(engine, handler)
 
-	svc := &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Handler: router,
+	// The admin listener is separate so that it can stay on a private
+	// interface; every route requires the bearer token.
+	adminEngine := gin.New()
+	adminHandler := admin.NewHandler(readOnly,
+		admin.WithConfigSource(reloader.config),
+		admin.WithRedactor(redactor),
+	)
+	if cfg.Admin.Enabled {
+		adminEngine.Use(middleware.LoggerWithAccessLog(log.Named("admin"), accessLog.Named("admin")))
+		adminEngine.Use(gin.RecoveryWithWriter(redactor.Writer(gin.DefaultErrorWriter)))
+		adminEngine.Use(middleware.BearerAuth(adminToken))
+		router.SetupAdmin(adminEngine, adminHandler)
+		router.SetupAudit(adminEngine, handler)
+	} else {
+		// Read-only mode must be switchable during an incident even without
+		// the admin listener.
+		router.SetupReadOnlySwitch(engine, adminHandler, middleware.BearerAuth(adminToken))
+		router.SetupAudit(engine, handler, middleware.BearerAuth(adminToken))
 	}
 
+	svc := server.New(fmt.Sprintf("%s:%d", cfg.Host, cfg.Port), engine, cfg.HTTP)
+
 	g := &run.Group{}
 
 	g.Add(func() error {
 		return svc.ListenAndServe()
 	}, func(err error) {
-		if err := svc.Shutdown(context.Background()); err != nil {
-			log.Errorf("failed to shutdown: %s", err)
+		log.Infof("draining requests for up to %s", cfg.HTTP.ShutdownTimeout)
+		if err := drainer.Shutdown(svc, cfg.HTTP.ShutdownTimeout); err != nil {
+			log.Errorf("failed to drain requests: %s", err)
 		}
 	})
 
+	if cfg.Admin.Enabled {
+		adminSvc := server.New(fmt.Sprintf("%s:%d", cfg.Admin.Host, cfg.Admin.Port), adminEngine, cfg.HTTP)
+		adminDrainer := &server.Drainer{}
+		g.Add(func() error {
+			return adminSvc.ListenAndServe()
+		}, func(err error) {
+			if err := adminDrainer.Shutdown(adminSvc, cfg.HTTP.ShutdownTimeout); err != nil {
+				log.Errorf("failed to shutdown admin listener: %s", err)
+			}
+		})
+	}
+
+	if cfg.Metrics.Enabled && cfg.Metrics.CollectInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			metrics.CollectCatalogStats(ctx, backend, cfg.Metrics.CollectInterval, log.Named("metrics"))
+			return nil
+		}, func(error) {
+			cancel()
+		})
+	}
+
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		hangup := make(chan os.Signal, 1)
+		signal.Notify(hangup, syscall.SIGHUP)
+		g.Add(func() error {
+			reload.Watch(ctx, source.path, cfg.Reload, hangup, reloader.reload)
+			return nil
+		}, func(error) {
+			signal.Stop(hangup)
+			cancel()
+		})
+	}
+
 	g.Add(run.SignalHandler(context.Background(), syscall.SIGINT, syscall.SIGTERM))
 
-	if err := g.Run(); err != nil {
-		log.Errorf("failed to run: %s", err)
+	runErr := g.Run()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		log.Errorf("failed to flush traces: %s", err)
+	}
+
+	if runErr != nil {
+		log.Errorf("failed to run: %s", runErr)
 		os.Exit(1)
 	}
 
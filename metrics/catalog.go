@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/table"
+)
+
+// instrumentedCatalog times every backend call.
+type instrumentedCatalog struct {
+	catalog.Catalog
+}
+
+// InstrumentCatalog wraps cat so that the latency of each operation is
+// exported as catalog_operation_duration_seconds.
+func InstrumentCatalog(cat catalog.Catalog) catalog.Catalog {
+	return &instrumentedCatalog{Catalog: cat}
+}
+
+func observe(operation string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	backendDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
+
+func (c *instrumentedCatalog) CreateTable(ctx context.Context, identifier table.Identifier, schema *iceberg.Schema, opts ...catalog.CreateTableOpt) (*table.Table, error) {
+	start := time.Now()
+	tbl, err := c.Catalog.CreateTable(ctx, identifier, schema, opts...)
+	observe("CreateTable", start, err)
+	return tbl, err
+}
+
+func (c *instrumentedCatalog) CommitTable(ctx context.Context, tbl *table.Table, reqs []table.Requirement, updates []table.Update) (table.Metadata, string, error) {
+	start := time.Now()
+	metadata, metadataLoc, err := c.Catalog.CommitTable(ctx, tbl, reqs, updates)
+	observe("CommitTable", start, err)
+	return metadata, metadataLoc, err
+}
+
+// ListTables observes the full iteration, which is when the backend is
+// actually queried.
+func (c *instrumentedCatalog) ListTables(ctx context.Context, namespace table.Identifier) iter.Seq2[table.Identifier, error] {
+	return func(yield func(table.Identifier, error) bool) {
+		start := time.Now()
+		var iterErr error
+		for ident, err := range c.Catalog.ListTables(ctx, namespace) {
+			if err != nil {
+				iterErr = err
+			}
+			if !yield(ident, err) {
+				break
+			}
+		}
+		observe("ListTables", start, iterErr)
+	}
+}
+
+func (c *instrumentedCatalog) LoadTable(ctx context.Context, identifier table.Identifier, props iceberg.Properties) (*table.Table, error) {
+	start := time.Now()
+	tbl, err := c.Catalog.LoadTable(ctx, identifier, props)
+	observe("LoadTable", start, err)
+	return tbl, err
+}
+
+func (c *instrumentedCatalog) DropTable(ctx context.Context, identifier table.Identifier) error {
+	start := time.Now()
+	err := c.Catalog.DropTable(ctx, identifier)
+	observe("DropTable", start, err)
+	return err
+}
+
+func (c *instrumentedCatalog) RenameTable(ctx context.Context, from, to table.Identifier) (*table.Table, error) {
+	start := time.Now()
+	tbl, err := c.Catalog.RenameTable(ctx, from, to)
+	observe("RenameTable", start, err)
+	return tbl, err
+}
+
+func (c *instrumentedCatalog) CheckTableExists(ctx context.Context, identifier table.Identifier) (bool, error) {
+	start := time.Now()
+	exists, err := c.Catalog.CheckTableExists(ctx, identifier)
+	observe("CheckTableExists", start, err)
+	return exists, err
+}
+
+func (c *instrumentedCatalog) ListNamespaces(ctx context.Context, parent table.Identifier) ([]table.Identifier, error) {
+	start := time.Now()
+	namespaces, err := c.Catalog.ListNamespaces(ctx, parent)
+	observe("ListNamespaces", start, err)
+	return namespaces, err
+}
+
+func (c *instrumentedCatalog) CreateNamespace(ctx context.Context, namespace table.Identifier, props iceberg.Properties) error {
+	start := time.Now()
+	err := c.Catalog.CreateNamespace(ctx, namespace, props)
+	observe("CreateNamespace", start, err)
+	return err
+}
+
+func (c *instrumentedCatalog) DropNamespace(ctx context.Context, namespace table.Identifier) error {
+	start := time.Now()
+	err := c.Catalog.DropNamespace(ctx, namespace)
+	observe("DropNamespace", start, err)
+	return err
+}
+
+func (c *instrumentedCatalog) CheckNamespaceExists(ctx context.Context, namespace table.Identifier) (bool, error) {
+	start := time.Now()
+	exists, err := c.Catalog.CheckNamespaceExists(ctx, namespace)
+	observe("CheckNamespaceExists", start, err)
+	return exists, err
+}
+
+func (c *instrumentedCatalog) LoadNamespaceProperties(ctx context.Context, namespace table.Identifier) (iceberg.Properties, error) {
+	start := time.Now()
+	props, err := c.Catalog.LoadNamespaceProperties(ctx, namespace)
+	observe("LoadNamespaceProperties", start, err)
+	return props, err
+}
+
+func (c *instrumentedCatalog) UpdateNamespaceProperties(ctx context.Context, namespace table.Identifier, removals []string, updates iceberg.Properties) (catalog.PropertiesUpdateSummary, error) {
+	start := time.Now()
+	summary, err := c.Catalog.UpdateNamespaceProperties(ctx, namespace, removals, updates)
+	observe("UpdateNamespaceProperties", start, err)
+	return summary, err
+}
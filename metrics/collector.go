@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/table"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+)
+
+// CollectCatalogStats refreshes the namespace and table gauges every interval
+// until ctx is done.
+func CollectCatalogStats(ctx context.Context, cat catalog.Catalog, interval time.Duration, log logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := RefreshCatalogStats(ctx, cat); err != nil && ctx.Err() == nil {
+			log.Errorf("failed to collect catalog stats: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshCatalogStats counts namespaces and tables once and updates the gauges.
+func RefreshCatalogStats(ctx context.Context, cat catalog.Catalog) error {
+	var tables int
+
+	// Walk the namespace tree breadth first; backends differ in whether
+	// listing without a parent already includes nested namespaces.
+	seen := map[string]bool{}
+	queue := []table.Identifier{nil}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+
+		children, err := cat.ListNamespaces(ctx, parent)
+		if err != nil {
+			return err
+		}
+		for _, ns := range children {
+			key := strings.Join(ns, "\x1F")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			queue = append(queue, ns)
+
+			for _, err := range cat.ListTables(ctx, ns) {
+				if err != nil {
+					return err
+				}
+				tables++
+			}
+		}
+	}
+
+	namespacesTotal.Set(float64(len(seen)))
+	tablesTotal.Set(float64(tables))
+	return nil
+}
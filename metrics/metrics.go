@@ -0,0 +1,221 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "iceberg_rest"
+
+const (
+	CommitSuccess  = "success"
+	CommitConflict = "conflict"
+	CommitError    = "error"
+)
+
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+const (
+	QueueFull    = "full"
+	QueueTimeout = "timeout"
+)
+
+type Config struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+	// CollectInterval is how often table and namespace counts are refreshed.
+	// Zero disables the collector.
+	CollectInterval time.Duration `yaml:"collect-interval"`
+}
+
+// Registry holds every metric exported by the server. It is separate from the
+// Prometheus default registry so that dependencies can't leak metrics into
+// /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route template, method and status.",
+	}, []string{"route", "method", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	RequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	slowRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_slow_requests_total",
+		Help:      "Requests over the latency or response size threshold by route template and reason.",
+	}, []string{"route", "reason"})
+
+	backendDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "catalog_operation_duration_seconds",
+		Help:      "Backend catalog call latency by operation and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "result"})
+
+	commitsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commits_total",
+		Help:      "Table commits by result: success, conflict or error.",
+	}, []string{"result"})
+
+	commitRetriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commit_retries_total",
+		Help:      "Commits rebased and retried on the server after a conflict.",
+	})
+
+	commitQueueWaiting = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "commit_queue_waiting",
+		Help:      "Commits waiting in the per-table commit queues.",
+	})
+
+	commitQueueRejectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commit_queue_rejected_total",
+		Help:      "Commits rejected by the commit queue by reason: full or timeout.",
+	}, []string{"reason"})
+
+	commitBatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "commit_batch_size",
+		Help:      "Queued commits applied together in one backend commit.",
+		Buckets:   prometheus.LinearBuckets(2, 2, 8),
+	})
+
+	cacheLookupsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "metadata_cache_lookups_total",
+		Help:      "Table metadata cache lookups by result: hit or miss.",
+	}, []string{"result"})
+
+	coalescedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "catalog_coalesced_calls_total",
+		Help:      "Catalog reads answered by a backend call shared with concurrent identical reads, by operation.",
+	}, []string{"operation"})
+
+	configReloadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Config reloads by result: success or failure.",
+	}, []string{"result"})
+
+	namespacesTotal = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "catalog_namespaces",
+		Help:      "Namespaces in the catalog as of the last collection.",
+	})
+
+	tablesTotal = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "catalog_tables",
+		Help:      "Tables in the catalog as of the last collection.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestsTotal,
+		requestDuration,
+		RequestsInFlight,
+		slowRequestsTotal,
+		backendDuration,
+		commitsTotal,
+		commitRetriesTotal,
+		commitQueueWaiting,
+		commitQueueRejectedTotal,
+		commitBatchSize,
+		cacheLookupsTotal,
+		coalescedTotal,
+		configReloadsTotal,
+		namespacesTotal,
+		tablesTotal,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a served HTTP request. route must be the route
+// template rather than the raw path to keep label cardinality bounded.
+func ObserveRequest(route, method string, status int, latency time.Duration) {
+	code := strconv.Itoa(status)
+	requestsTotal.WithLabelValues(route, method, code).Inc()
+	requestDuration.WithLabelValues(route, method, code).Observe(latency.Seconds())
+}
+
+// RecordSlowRequest counts a request flagged for reason, either "latency" or
+// "size".
+func RecordSlowRequest(route, reason string) {
+	slowRequestsTotal.WithLabelValues(route, reason).Inc()
+}
+
+// RecordCommitRetry counts a server side commit retry.
+func RecordCommitRetry() {
+	commitRetriesTotal.Inc()
+}
+
+// RecordConfigReload counts a config reload by result, either "success" or
+// "failure".
+func RecordConfigReload(result string) {
+	configReloadsTotal.WithLabelValues(result).Inc()
+}
+
+// RecordCommitQueueWaiting adds delta to the number of commits waiting in the
+// commit queues.
+func RecordCommitQueueWaiting(delta int) {
+	commitQueueWaiting.Add(float64(delta))
+}
+
+// RecordCommitQueueRejected counts a commit turned away by the commit queue
+// for reason, either "full" or "timeout".
+func RecordCommitQueueRejected(reason string) {
+	commitQueueRejectedTotal.WithLabelValues(reason).Inc()
+}
+
+// ObserveCommitBatch records the number of queued commits applied together.
+func ObserveCommitBatch(size int) {
+	commitBatchSize.Observe(float64(size))
+}
+
+// RecordCacheLookup counts a metadata cache lookup by its result.
+func RecordCacheLookup(result string) {
+	cacheLookupsTotal.WithLabelValues(result).Inc()
+}
+
+// RecordCoalesced counts a read that shared its backend call with others.
+func RecordCoalesced(operation string) {
+	coalescedTotal.WithLabelValues(operation).Inc()
+}
+
+// RecordCommit counts a table commit by its result.
+func RecordCommit(result string) {
+	commitsTotal.WithLabelValues(result).Inc()
+}
@@ -0,0 +1,222 @@
+package redact
+
+import (
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every redacted value.
+const Mask = "****"
+
+// minSecretLength guards against scrubbing short values such as "1" or
+// "true" out of unrelated log lines.
+const minSecretLength = 4
+
+// DefaultKeys are the credential properties understood by iceberg-go.
+var DefaultKeys = []string{
+	"s3.access-key-id",
+	"s3.secret-access-key",
+	"s3.session-token",
+	"glue.access-key-id",
+	"glue.secret-access-key",
+	"glue.session-token",
+	"gcs.jsonkey",
+	"adls.auth.shared-key.account.key",
+	"token",
+	"credential",
+}
+
+// DefaultPatterns match keys that look sensitive even if they are unknown.
+var DefaultPatterns = []string{
+	`(?i)secret`,
+	`(?i)password`,
+	`(?i)token`,
+	`(?i)credential`,
+	`(?i)private-key`,
+	`(?i)account\.key`,
+	`^adls\.sas-token\.`,
+}
+
+var urlPassword = regexp.MustCompile(`(://[^:/@\s]+:)[^@/\s]+@`)
+
+type Config struct {
+	// Keys and Patterns extend the built-in sensitive property keys.
+	Keys     []string `yaml:"keys"`
+	Patterns []string `yaml:"patterns"`
+}
+
+// Redactor masks sensitive properties and scrubs known secret values from
+// free text such as log lines and error messages.
+type Redactor struct {
+	keys     map[string]struct{}
+	patterns []*regexp.Regexp
+
+	mu       sync.RWMutex
+	secrets  map[string]struct{}
+	replacer *strings.Replacer
+}
+
+func New(cfg Config) (*Redactor, error) {
+	r := &Redactor{
+		keys:     map[string]struct{}{},
+		secrets:  map[string]struct{}{},
+		replacer: strings.NewReplacer(),
+	}
+	for _, key := range slices.Concat(DefaultKeys, cfg.Keys) {
+		r.keys[key] = struct{}{}
+	}
+	for _, pattern := range slices.Concat(DefaultPatterns, cfg.Patterns) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Default returns a redactor with only the built-in keys and patterns.
+func Default() *Redactor {
+	r, err := New(Config{})
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// IsSensitive reports whether values of the property key must not leave the
+// process.
+func (r *Redactor) IsSensitive(key string) bool {
+	if _, ok := r.keys[key]; ok {
+		return true
+	}
+	for _, re := range r.patterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddSecrets remembers the values of sensitive keys in props so that String
+// can scrub them wherever they show up.
+func (r *Redactor) AddSecrets(props map[string]string) {
+	values := make([]string, 0, len(props))
+	for key, value := range props {
+		if r.IsSensitive(key) {
+			values = append(values, value)
+		}
+	}
+	r.AddValues(values...)
+}
+
+// AddValues remembers secret values whatever key they are set under, such as
+// those resolved from secret references.
+func (r *Redactor) AddValues(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	added := false
+	for _, value := range values {
+		if len(value) < minSecretLength {
+			continue
+		}
+		if _, ok := r.secrets[value]; !ok {
+			r.secrets[value] = struct{}{}
+			added = true
+		}
+	}
+	if !added {
+		return
+	}
+
+	known := make([]string, 0, len(r.secrets))
+	for value := range r.secrets {
+		known = append(known, value)
+	}
+	// Replace longer secrets first so that one containing another is masked
+	// as a whole.
+	sort.Slice(known, func(i, j int) bool { return len(known[i]) > len(known[j]) })
+
+	oldnew := make([]string, 0, 2*len(known))
+	for _, value := range known {
+		oldnew = append(oldnew, value, Mask)
+	}
+	r.replacer = strings.NewReplacer(oldnew...)
+}
+
+// String masks known secret values and passwords embedded in URLs.
+func (r *Redactor) String(s string) string {
+	r.mu.RLock()
+	replacer := r.replacer
+	r.mu.RUnlock()
+
+	return urlPassword.ReplaceAllString(replacer.Replace(s), "${1}"+Mask+"@")
+}
+
+// Properties returns a copy of props with sensitive values masked and secrets
+// scrubbed from the remaining values.
+func (r *Redactor) Properties(props map[string]string) map[string]string {
+	if props == nil {
+		return nil
+	}
+
+	redacted := make(map[string]string, len(props))
+	for key, value := range props {
+		if r.IsSensitive(key) {
+			redacted[key] = Mask
+			continue
+		}
+		redacted[key] = r.String(value)
+	}
+	return redacted
+}
+
+// Tree returns a copy of a decoded YAML or JSON document with the values of
+// sensitive keys masked at any depth and secrets scrubbed from other strings.
+func (r *Redactor) Tree(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for key, value := range v {
+			if _, nested := value.(map[string]any); !nested && r.IsSensitive(key) {
+				redacted[key] = Mask
+				continue
+			}
+			redacted[key] = r.Tree(value)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(v))
+		for i, value := range v {
+			redacted[i] = r.Tree(value)
+		}
+		return redacted
+	case string:
+		return r.String(v)
+	default:
+		return v
+	}
+}
+
+type writer struct {
+	w io.Writer
+	r *Redactor
+}
+
+// Writer scrubs secrets from everything written to w. Each Write is redacted
+// on its own, so callers should write whole lines.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return &writer{w: w, r: r}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/cache"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+	"github.com/xixipi-lining/iceberg-rest-catalog/metrics"
+	"github.com/xixipi-lining/iceberg-rest-catalog/redact"
+	"github.com/xixipi-lining/iceberg-rest-catalog/reload"
+)
+
+// reloadableKeys are the top-level config keys a reload applies. Changes to
+// any other key are reported and take effect after a restart.
+var reloadableKeys = map[string]bool{
+	"catalog":   true,
+	"server":    true,
+	"log":       true,
+	"read-only": true,
+}
+
+// configReloader re-reads the config file and applies it to the running
+// server. The new config is checked and the catalog reopened before anything
+// is swapped, so a config that fails either leaves the running one untouched.
+type configReloader struct {
+	source configSource
+
+	current atomic.Pointer[Config]
+	// props are the resolved properties of the served catalog, so that a
+	// rotated secret reopens it even if the references are unchanged.
+	props    atomic.Pointer[map[string]string]
+	backend  *reload.Catalog
+	cache    *cache.Catalog
+	handler  *handlers.CatalogHandler
+	readOnly *middleware.ReadOnly
+	redactor *redact.Redactor
+	log      logger.Logger
+	// loggers are the roots whose outputs follow the log section.
+	loggers []logger.Logger
+
+	// mu serializes reloads triggered by SIGHUP and by the file watch.
+	mu sync.Mutex
+}
+
+// config returns the effective config.
+func (r *configReloader) config() any {
+	return r.current.Load()
+}
+
+// catalogProps returns the resolved properties of the served catalog.
+func (r *configReloader) catalogProps() map[string]string {
+	return *r.props.Load()
+}
+
+func (r *configReloader) reload() {
+	if err := r.apply(); err != nil {
+		metrics.RecordConfigReload("failure")
+		r.log.Errorf("rejected config reload, keeping the running config: %s", r.redactor.String(err.Error()))
+		return
+	}
+	metrics.RecordConfigReload("success")
+}
+
+func (r *configReloader) apply() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.current.Load()
+	next, err := r.source.reload()
+	if err != nil {
+		return err
+	}
+	if err := next.Validate(); err != nil {
+		return err
+	}
+
+	// The served catalog keeps its name, only its properties can change.
+	name := current.DefaultCatalog
+	if _, ok := next.Catalogs[name]; !ok {
+		return fmt.Errorf("catalog: served catalog %q is no longer defined", name)
+	}
+	// Known before loading so that an error quoting them is scrubbed.
+	props, err := resolveCatalog(context.Background(), next.Catalogs[name], r.redactor)
+	if err != nil {
+		return fmt.Errorf("resolving secrets of catalog %s: %w", name, err)
+	}
+	var reopened catalog.Catalog
+	if !maps.Equal(props, r.catalogProps()) {
+		reopened, err = catalog.Load(context.Background(), name, maps.Clone(props))
+		if err != nil {
+			return fmt.Errorf("reopening catalog %s: %w", name, err)
+		}
+	}
+
+	r.redactor.AddSecrets(next.ServerConfig.Defaults)
+	r.redactor.AddSecrets(next.ServerConfig.Overrides)
+	if reopened != nil {
+		// Calls still running on the replaced catalog finish there before it
+		// is closed.
+		closed := r.backend.Swap(reopened)
+		go func() {
+			if err := <-closed; err != nil {
+				r.log.Warnf("failed to close replaced catalog %s: %s", name, r.redactor.String(err.Error()))
+			}
+		}()
+		r.props.Store(&props)
+		if r.cache != nil {
+			// Cached tables carry a FileIO configured from the old properties.
+			r.cache.Purge()
+		}
+		r.log.Infof("reopened catalog %s", name)
+	}
+	r.handler.SetConfig(next.ServerConfig)
+	// Reloading reopens the log files, so only a changed section does.
+	if !reflect.DeepEqual(current.LogConfig, next.LogConfig) {
+		for _, l := range r.loggers {
+			logger.Reload(l, &next.LogConfig)
+		}
+	}
+	// Only a changed section overrides what was switched through the admin
+	// API since.
+	if !reflect.DeepEqual(current.ReadOnly, next.ReadOnly) {
+		r.readOnly.Set(next.ReadOnly)
+	}
+
+	if keys := restartRequired(current, next); len(keys) > 0 {
+		r.log.Warnf("changes to %s take effect after a restart", strings.Join(keys, ", "))
+	}
+
+	effective := *current
+	effective.Catalogs = next.Catalogs
+	effective.ServerConfig = next.ServerConfig
+	effective.LogConfig = next.LogConfig
+	effective.ReadOnly = next.ReadOnly
+	r.current.Store(&effective)
+
+	r.log.Infof("reloaded config from %s", r.source.path)
+	return nil
+}
+
+// restartRequired lists the top-level keys outside reloadableKeys that differ
+// between old and next.
+func restartRequired(old, next *Config) []string {
+	oldValue, nextValue := reflect.ValueOf(*old), reflect.ValueOf(*next)
+	var keys []string
+	for i := range oldValue.NumField() {
+		key, _, _ := strings.Cut(oldValue.Type().Field(i).Tag.Get("yaml"), ",")
+		if reloadableKeys[key] {
+			continue
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,167 @@
+package reload
+
+import (
+	"context"
+	"io"
+	"iter"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/table"
+)
+
+// Catalog forwards every call to the catalog most recently passed to Swap.
+// It sits right on top of the backend so that the decorators, the commit
+// queue and the probes built at startup keep working after a reload.
+//
+// A call that already started finishes on the catalog it started on. The
+// replaced catalog is closed once the last of those calls returns.
+type Catalog struct {
+	current atomic.Pointer[backend]
+}
+
+// backend is a catalog with the calls running on it. The calls hold mu for
+// reading, closing it takes mu for writing.
+type backend struct {
+	catalog.Catalog
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewCatalog(cat catalog.Catalog) *Catalog {
+	c := &Catalog{}
+	c.current.Store(&backend{Catalog: cat})
+	return c
+}
+
+// Swap makes cat serve the following calls. The catalog it replaces is
+// closed in the background, if it implements io.Closer, once the calls
+// running on it have returned. The returned channel receives the result of
+// closing it.
+func (c *Catalog) Swap(cat catalog.Catalog) <-chan error {
+	old := c.current.Swap(&backend{Catalog: cat})
+	closed := make(chan error, 1)
+	go func() {
+		old.mu.Lock()
+		old.closed = true
+		old.mu.Unlock()
+		var err error
+		if closer, ok := old.Catalog.(io.Closer); ok {
+			err = closer.Close()
+		}
+		closed <- err
+	}()
+	return closed
+}
+
+// Current returns the catalog serving calls.
+func (c *Catalog) Current() catalog.Catalog {
+	return c.current.Load().Catalog
+}
+
+// acquire returns the catalog serving calls, which stays open until release
+// is called.
+func (c *Catalog) acquire() (cat catalog.Catalog, release func()) {
+	for {
+		b := c.current.Load()
+		b.mu.RLock()
+		if !b.closed {
+			return b.Catalog, b.mu.RUnlock
+		}
+		// Swapped out and closed between loading and locking it.
+		b.mu.RUnlock()
+	}
+}
+
+func (c *Catalog) CatalogType() catalog.Type {
+	cat, release := c.acquire()
+	defer release()
+	return cat.CatalogType()
+}
+
+func (c *Catalog) CreateTable(ctx context.Context, identifier table.Identifier, schema *iceberg.Schema, opts ...catalog.CreateTableOpt) (*table.Table, error) {
+	cat, release := c.acquire()
+	defer release()
+	return cat.CreateTable(ctx, identifier, schema, opts...)
+}
+
+func (c *Catalog) CommitTable(ctx context.Context, tbl *table.Table, reqs []table.Requirement, updates []table.Update) (table.Metadata, string, error) {
+	cat, release := c.acquire()
+	defer release()
+	return cat.CommitTable(ctx, tbl, reqs, updates)
+}
+
+func (c *Catalog) ListTables(ctx context.Context, namespace table.Identifier) iter.Seq2[table.Identifier, error] {
+	return func(yield func(table.Identifier, error) bool) {
+		cat, release := c.acquire()
+		defer release()
+		for identifier, err := range cat.ListTables(ctx, namespace) {
+			if !yield(identifier, err) {
+				return
+			}
+		}
+	}
+}
+
+func (c *Catalog) LoadTable(ctx context.Context, identifier table.Identifier, props iceberg.Properties) (*table.Table, error) {
+	cat, release := c.acquire()
+	defer release()
+	return cat.LoadTable(ctx, identifier, props)
+}
+
+func (c *Catalog) DropTable(ctx context.Context, identifier table.Identifier) error {
+	cat, release := c.acquire()
+	defer release()
+	return cat.DropTable(ctx, identifier)
+}
+
+func (c *Catalog) RenameTable(ctx context.Context, from, to table.Identifier) (*table.Table, error) {
+	cat, release := c.acquire()
+	defer release()
+	return cat.RenameTable(ctx, from, to)
+}
+
+func (c *Catalog) CheckTableExists(ctx context.Context, identifier table.Identifier) (bool, error) {
+	cat, release := c.acquire()
+	defer release()
+	return cat.CheckTableExists(ctx, identifier)
+}
+
+func (c *Catalog) ListNamespaces(ctx context.Context, parent table.Identifier) ([]table.Identifier, error) {
+	cat, release := c.acquire()
+	defer release()
+	return cat.ListNamespaces(ctx, parent)
+}
+
+func (c *Catalog) CreateNamespace(ctx context.Context, namespace table.Identifier, props iceberg.Properties) error {
+	cat, release := c.acquire()
+	defer release()
+	return cat.CreateNamespace(ctx, namespace, props)
+}
+
+func (c *Catalog) DropNamespace(ctx context.Context, namespace table.Identifier) error {
+	cat, release := c.acquire()
+	defer release()
+	return cat.DropNamespace(ctx, namespace)
+}
+
+func (c *Catalog) CheckNamespaceExists(ctx context.Context, namespace table.Identifier) (bool, error) {
+	cat, release := c.acquire()
+	defer release()
+	return cat.CheckNamespaceExists(ctx, namespace)
+}
+
+func (c *Catalog) LoadNamespaceProperties(ctx context.Context, namespace table.Identifier) (iceberg.Properties, error) {
+	cat, release := c.acquire()
+	defer release()
+	return cat.LoadNamespaceProperties(ctx, namespace)
+}
+
+func (c *Catalog) UpdateNamespaceProperties(ctx context.Context, namespace table.Identifier, removals []string, updates iceberg.Properties) (catalog.PropertiesUpdateSummary, error) {
+	cat, release := c.acquire()
+	defer release()
+	return cat.UpdateNamespaceProperties(ctx, namespace, removals, updates)
+}
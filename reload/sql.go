@@ -0,0 +1,60 @@
+package reload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	sqlcat "github.com/apache/iceberg-go/catalog/sql"
+)
+
+// The SQL catalog opens a database pool it never closes. Its registration is
+// replaced by one that opens the pool the same way but returns a catalog
+// that closes it, so that Swap can release the pool of a replaced catalog.
+func init() {
+	catalog.Register(string(catalog.SQL), catalog.RegistrarFunc(openSQL))
+}
+
+// sqlCatalog is a SQL catalog that closes its database pool.
+type sqlCatalog struct {
+	*sqlcat.Catalog
+	db *sql.DB
+}
+
+func (c *sqlCatalog) Close() error {
+	return c.db.Close()
+}
+
+func openSQL(_ context.Context, name string, p iceberg.Properties) (_ catalog.Catalog, err error) {
+	driver, ok := p[sqlcat.DriverKey]
+	if !ok {
+		return nil, errors.New("must provide driver to pass to sql.Open")
+	}
+	dialect := strings.ToLower(p[sqlcat.DialectKey])
+	if dialect == "" {
+		return nil, errors.New("must provide sql dialect to use")
+	}
+
+	db, err := sql.Open(driver, strings.TrimPrefix(p.Get("uri", ""), "sql://"))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to create SQL catalog: %v", r)
+		}
+		if err != nil {
+			db.Close()
+		}
+	}()
+
+	cat, err := sqlcat.NewCatalog(p.Get(name, "sql"), db, sqlcat.SupportedDialect(dialect), p)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlCatalog{Catalog: cat, db: db}, nil
+}
@@ -0,0 +1,64 @@
+// Package reload re-applies the config file while the server keeps running.
+package reload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"time"
+)
+
+type Config struct {
+	// Watch polls the config file and reloads it when its content changes.
+	// SIGHUP reloads it either way.
+	Watch    bool          `yaml:"watch"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Watch calls reload whenever trigger fires or, if cfg.Watch is set, the
+// content of the file at path changes, until ctx is done.
+//
+// The content is polled rather than watched through inotify so that files
+// swapped behind a symlink, as in Kubernetes ConfigMap volumes, are noticed
+// too. A file that can't be read is skipped until it can, since editors and
+// volume updates briefly remove it.
+func Watch(ctx context.Context, path string, cfg Config, trigger <-chan os.Signal, reload func()) {
+	var tick <-chan time.Time
+	if cfg.Watch && cfg.Interval > 0 {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	last, _ := checksum(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-trigger:
+			// Reloading on request also catches up with the file, so the
+			// poll doesn't reload it a second time.
+			if sum, err := checksum(path); err == nil {
+				last = sum
+			}
+			reload()
+		case <-tick:
+			sum, err := checksum(path)
+			if err != nil || bytes.Equal(sum, last) {
+				continue
+			}
+			last = sum
+			reload()
+		}
+	}
+}
+
+func checksum(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/cache"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+	"github.com/xixipi-lining/iceberg-rest-catalog/redact"
+	"github.com/xixipi-lining/iceberg-rest-catalog/reload"
+)
+
+// reloadConfig is a config file serving a SQLite catalog in dbDir.
+func reloadConfig(dbDir, logFile, extra string) string {
+	return fmt.Sprintf(`default-catalog: default
+catalog:
+  default:
+    type: sql
+    uri: file:%s
+    sql.driver: sqlite3
+    sql.dialect: sqlite
+    init_catalog_tables: "true"
+    warehouse: file://%s
+log:
+  outputs:
+    - type: file
+      file-name: %s
+%s`, filepath.Join(dbDir, "catalog.db"), filepath.Join(dbDir, "warehouse"), logFile, extra)
+}
+
+// newTestReloader wires a reloader the way main does, around the config file
+// at path.
+func newTestReloader(t *testing.T, path string) (*configReloader, *gin.Engine) {
+	t.Helper()
+	source := configSource{path: path}
+	cfg, err := source.load()
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	redactor, err := redact.New(cfg.Redact)
+	require.NoError(t, err)
+	props, err := resolveCatalog(context.Background(), cfg.Catalogs[cfg.DefaultCatalog], redactor)
+	require.NoError(t, err)
+	cat, err := catalog.Load(context.Background(), cfg.DefaultCatalog, maps.Clone(props))
+	require.NoError(t, err)
+
+	backend := reload.NewCatalog(cat)
+	cached := cache.New(backend, cache.Config{Enabled: true, TTL: time.Minute})
+	handler := handlers.NewCatalogHandler(cached, cfg.ServerConfig, handlers.WithMetadataEncoder(cached))
+	log := logger.NewLogger(&cfg.LogConfig)
+	t.Cleanup(func() { logger.NewLogger(&logger.Config{}) })
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	router.Setup(engine, handler)
+
+	r := &configReloader{
+		source:   source,
+		backend:  backend,
+		cache:    cached,
+		handler:  handler,
+		readOnly: middleware.NewReadOnly(cfg.ReadOnly),
+		redactor: redactor,
+		log:      log.Named("reload"),
+		loggers:  []logger.Logger{log},
+	}
+	r.current.Store(cfg)
+	r.props.Store(&props)
+	return r, engine
+}
+
+func TestConfigReloaderApply(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	logFile := filepath.Join(dir, "server.log")
+	first, second := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(path, []byte(reloadConfig(first, logFile, "port: 8080\n")), 0o600))
+
+	r, engine := newTestReloader(t, path)
+	served := r.backend.Current()
+
+	ident := table.Identifier{"ns", "events"}
+	schema := iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64})
+	require.NoError(t, r.cache.CreateNamespace(ctx, ident[:1], nil))
+	_, err := r.cache.CreateTable(ctx, ident, schema)
+	require.NoError(t, err)
+	_, err = r.cache.LoadTable(ctx, ident, nil)
+	require.NoError(t, err)
+
+	t.Run("Rejected", func(t *testing.T) {
+		before := r.current.Load()
+		require.NoError(t, os.WriteFile(path, []byte(reloadConfig(second, logFile, "port: -1\n")), 0o600))
+		require.Error(t, r.apply())
+
+		assert.Same(t, before, r.current.Load())
+		assert.Same(t, served, r.backend.Current(), "a rejected config keeps the catalog")
+		_, err := r.cache.LoadTable(ctx, ident, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(reloadConfig(first, logFile, "port: 8080\n")), 0o600))
+		require.NoError(t, r.apply())
+		assert.Same(t, served, r.backend.Current(), "unchanged properties keep the catalog")
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		r.source.optional = true
+		defer func() { r.source.optional = false }()
+		require.NoError(t, os.Remove(path))
+
+		assert.ErrorIs(t, r.apply(), fs.ErrNotExist, "a missing file doesn't fall back to the local catalog")
+		assert.Same(t, served, r.backend.Current())
+	})
+
+	t.Run("Applied", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(reloadConfig(second, logFile, `port: 9090
+server:
+  defaults:
+    warehouse: s3://reloaded/
+read-only:
+  enabled: true
+`)), 0o600))
+		require.NoError(t, r.apply())
+
+		assert.NotSame(t, served, r.backend.Current(), "changed properties reopen the catalog")
+		assert.Equal(t, "file:"+filepath.Join(second, "catalog.db"), r.catalogProps()["uri"], "probes read the reopened catalog's properties")
+		require.Eventually(t, func() bool {
+			return served.CreateNamespace(ctx, table.Identifier{"closed"}, nil) != nil
+		}, 5*time.Second, 10*time.Millisecond, "the replaced catalog is closed")
+
+		_, err := r.cache.LoadTable(ctx, ident, nil)
+		assert.ErrorIs(t, err, catalog.ErrNoSuchTable, "the cache is purged")
+
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/config", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var cfg handlers.Config
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cfg))
+		assert.Equal(t, "s3://reloaded/", cfg.Defaults["warehouse"])
+
+		assert.True(t, r.readOnly.Get().Enabled)
+
+		effective := r.current.Load()
+		assert.Equal(t, 8080, effective.Port, "the port takes effect after a restart")
+		assert.Equal(t, "s3://reloaded/", effective.ServerConfig.Defaults["warehouse"])
+
+		log, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(log), "changes to port take effect after a restart")
+	})
+}
+
+func TestRestartRequired(t *testing.T) {
+	old := defaultConfig()
+	next := defaultConfig()
+	assert.Empty(t, restartRequired(old, next))
+
+	next.Catalogs = map[string]iceberg.Properties{"default": {"type": "sql"}}
+	next.ServerConfig.Defaults = map[string]string{"warehouse": "s3://new/"}
+	next.LogConfig.Debug = true
+	next.ReadOnly.Enabled = true
+	assert.Empty(t, restartRequired(old, next), "reloadable keys")
+
+	next.Port = 9090
+	next.Cache.Enabled = !old.Cache.Enabled
+	next.DefaultCatalog = "other"
+	assert.Equal(t, []string{"default-catalog", "cache", "port"}, restartRequired(old, next))
+}
@@ -0,0 +1,173 @@
+// Package secret resolves references such as ${env:NAME} or
+// ${file:/run/secrets/name} in config values, so that secrets are kept out of
+// the config file.
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Provider looks up the secrets of one scheme, e.g. "env" for ${env:NAME}.
+// ref is the part after the colon.
+type Provider interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+type ProviderFunc func(ctx context.Context, ref string) (string, error)
+
+func (f ProviderFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// Env resolves ${env:NAME} to the environment variable NAME, which must be
+// set.
+var Env = ProviderFunc(func(_ context.Context, ref string) (string, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref)
+	}
+	return value, nil
+})
+
+// File resolves ${file:PATH} to the content of PATH without its trailing
+// newline, as mounted by Kubernetes or Docker secrets.
+var File = ProviderFunc(func(_ context.Context, ref string) (string, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+})
+
+// Resolver expands references through the providers registered for their
+// scheme. "$${" stands for a literal "${".
+type Resolver struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+// NewResolver returns a resolver with the env and file providers.
+func NewResolver() *Resolver {
+	return &Resolver{providers: map[string]Provider{
+		"env":  Env,
+		"file": File,
+	}}
+}
+
+// Register makes p resolve the references of scheme, replacing the provider
+// registered before, if any.
+func (r *Resolver) Register(scheme string, p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[scheme] = p
+}
+
+func (r *Resolver) provider(scheme string) (Provider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[scheme]
+	return p, ok
+}
+
+// HasReference reports whether value holds a reference to resolve.
+func HasReference(value string) bool {
+	return strings.Contains(strings.ReplaceAll(value, "$${", ""), "${")
+}
+
+// Check reports malformed references and references to unknown schemes in
+// value without resolving them.
+func (r *Resolver) Check(value string) error {
+	_, err := r.expand(value, func(string, string) (string, error) {
+		return "", nil
+	})
+	return err
+}
+
+// Resolve returns value with every reference replaced by its secret. Errors
+// name the reference, never the secret.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	resolved, _, err := r.resolve(ctx, value)
+	return resolved, err
+}
+
+func (r *Resolver) resolve(ctx context.Context, value string) (string, []string, error) {
+	var secrets []string
+	resolved, err := r.expand(value, func(scheme, ref string) (string, error) {
+		p, _ := r.provider(scheme)
+		secret, err := p.Resolve(ctx, ref)
+		if err != nil {
+			return "", fmt.Errorf("resolving ${%s:%s}: %w", scheme, ref, err)
+		}
+		secrets = append(secrets, secret)
+		return secret, nil
+	})
+	return resolved, secrets, err
+}
+
+// Properties returns a copy of props with the references in its values
+// resolved, along with the secrets they resolved to. Every property that
+// fails is reported, keyed by its name.
+func (r *Resolver) Properties(ctx context.Context, props map[string]string) (map[string]string, []string, error) {
+	resolved := maps.Clone(props)
+	var (
+		secrets []string
+		errs    []error
+	)
+	for _, key := range slices.Sorted(maps.Keys(props)) {
+		if !strings.Contains(props[key], "${") {
+			continue
+		}
+		value, values, err := r.resolve(ctx, props[key])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		resolved[key] = value
+		secrets = append(secrets, values...)
+	}
+	return resolved, secrets, errors.Join(errs...)
+}
+
+func (r *Resolver) expand(value string, lookup func(scheme, ref string) (string, error)) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(value, "${")
+		if i < 0 {
+			b.WriteString(value)
+			return b.String(), nil
+		}
+		if i > 0 && value[i-1] == '$' {
+			// An escaped "$${".
+			b.WriteString(value[:i-1])
+			b.WriteString("${")
+			value = value[i+2:]
+			continue
+		}
+		b.WriteString(value[:i])
+
+		end := strings.IndexByte(value[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated reference %q", value[i:])
+		}
+		scheme, ref, ok := strings.Cut(value[i+2:i+end], ":")
+		if !ok || scheme == "" || ref == "" {
+			return "", fmt.Errorf("malformed reference %q, expected ${scheme:name}", value[i:i+end+1])
+		}
+		if _, ok := r.provider(scheme); !ok {
+			return "", fmt.Errorf("unknown secret provider %q", scheme)
+		}
+		secret, err := lookup(scheme, ref)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(secret)
+		value = value[i+end+1:]
+	}
+}
@@ -0,0 +1,141 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/admin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+)
+
+func TestAdminEndpoints(t *testing.T) {
+	const token = "admin-secret"
+
+	type config struct {
+		Catalogs map[string]map[string]string `yaml:"catalog"`
+		Admin    admin.Config                 `yaml:"admin"`
+	}
+	cfg := config{
+		Catalogs: map[string]map[string]string{
+			"default": {
+				"uri":                  "postgres://iceberg:hunter22@db/iceberg",
+				"s3.secret-access-key": "aws-secret",
+				"warehouse":            "s3://bucket",
+			},
+		},
+		Admin: admin.Config{Enabled: true, Port: 9090, Token: token},
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.BearerAuth(token))
+	router.SetupAdmin(engine, admin.NewHandler(middleware.NewReadOnly(middleware.ReadOnlyConfig{}),
+		admin.WithConfig(cfg),
+	))
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	do := func(method, path, bearer string, body any) (int, []byte) {
+		var reader io.Reader
+		if body != nil {
+			raw, err := json.Marshal(body)
+			require.NoError(t, err)
+			reader = bytes.NewReader(raw)
+		}
+		req, err := http.NewRequest(method, server.URL+path, reader)
+		require.NoError(t, err)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		raw, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, raw
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		status, _ := do(http.MethodGet, "/admin/build-info", "", nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		status, _ = do(http.MethodGet, "/admin/build-info", "wrong", nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("LogLevel", func(t *testing.T) {
+		global, _ := logger.Levels()
+		defer func() {
+			require.NoError(t, logger.SetLevel("", global))
+			logger.ResetLevel("http")
+		}()
+
+		status, raw := do(http.MethodPut, "/admin/log-level", token, admin.SetLogLevelRequest{Level: "warn"})
+		require.Equal(t, http.StatusOK, status)
+		status, raw = do(http.MethodPut, "/admin/log-level", token, admin.SetLogLevelRequest{Component: "http", Level: "trace"})
+		require.Equal(t, http.StatusOK, status)
+
+		var levels admin.LogLevelResponse
+		require.NoError(t, json.Unmarshal(raw, &levels))
+		assert.Equal(t, "warn", levels.Level)
+		assert.Equal(t, map[string]string{"http": "trace"}, levels.Components)
+
+		for _, req := range []admin.SetLogLevelRequest{
+			{Level: "loud"},
+			{},
+			{Level: "99"},
+			{Component: "http", Level: "-1"},
+		} {
+			status, _ = do(http.MethodPut, "/admin/log-level", token, req)
+			assert.Equal(t, http.StatusBadRequest, status, "%+v", req)
+		}
+		status, raw = do(http.MethodGet, "/admin/log-level", token, nil)
+		require.Equal(t, http.StatusOK, status)
+		levels = admin.LogLevelResponse{}
+		require.NoError(t, json.Unmarshal(raw, &levels))
+		assert.Equal(t, "warn", levels.Level, "rejected levels change nothing")
+		assert.Equal(t, map[string]string{"http": "trace"}, levels.Components)
+
+		status, raw = do(http.MethodPut, "/admin/log-level", token, admin.SetLogLevelRequest{Component: "http"})
+		require.Equal(t, http.StatusOK, status)
+		levels = admin.LogLevelResponse{}
+		require.NoError(t, json.Unmarshal(raw, &levels))
+		assert.Empty(t, levels.Components)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		status, raw := do(http.MethodGet, "/admin/config", token, nil)
+		require.Equal(t, http.StatusOK, status)
+		assert.NotContains(t, string(raw), "hunter22")
+		assert.NotContains(t, string(raw), "aws-secret")
+		assert.NotContains(t, string(raw), token)
+		assert.Contains(t, string(raw), "s3://bucket")
+	})
+
+	t.Run("Diagnostics", func(t *testing.T) {
+		status, raw := do(http.MethodGet, "/admin/build-info", token, nil)
+		require.Equal(t, http.StatusOK, status)
+		var info admin.BuildInfoResponse
+		require.NoError(t, json.Unmarshal(raw, &info))
+		assert.Equal(t, admin.Version, info.Version)
+		assert.NotEmpty(t, info.GoVersion)
+
+		status, raw = do(http.MethodGet, "/admin/goroutines", token, nil)
+		require.Equal(t, http.StatusOK, status)
+		assert.Contains(t, string(raw), "goroutine ")
+
+		status, _ = do(http.MethodGet, "/debug/pprof/", token, nil)
+		assert.Equal(t, http.StatusOK, status)
+		status, _ = do(http.MethodGet, "/debug/pprof/heap", token, nil)
+		assert.Equal(t, http.StatusOK, status)
+	})
+}
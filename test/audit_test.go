@@ -0,0 +1,141 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/audit"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+)
+
+func TestAuditLog(t *testing.T) {
+	for _, sinkType := range []string{"jsonl", "sqlite"} {
+		t.Run(sinkType, func(t *testing.T) {
+			sink, err := audit.NewSink(&audit.Config{
+				Sink: sinkType,
+				Path: filepath.Join(t.TempDir(), "audit."+sinkType),
+			})
+			require.NoError(t, err)
+			defer sink.Close()
+
+			backendCatalog := newSQLCatalog(t)
+
+			handler := handlers.NewCatalogHandler(backendCatalog, handlers.Config{}, handlers.WithAuditSink(sink))
+
+			gin.SetMode(gin.TestMode)
+			engine := gin.New()
+			engine.Use(middleware.Logger(logger.NewLogger(&logger.Config{})))
+			// The REST client cannot set custom headers, so stand in for the
+			// authenticating proxy here.
+			engine.Use(func(c *gin.Context) {
+				c.Request.Header.Set("X-Principal", "alice")
+			})
+			engine.Use(middleware.Principal("X-Principal"))
+			router.Setup(engine, handler)
+			router.SetupAudit(engine, handler, middleware.BearerAuth("auditor-token"))
+
+			server := httptest.NewServer(engine)
+			defer server.Close()
+
+			restCatalog, err := rest.NewCatalog(context.Background(), "test-client", server.URL)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			require.NoError(t, restCatalog.CreateNamespace(ctx, table.Identifier{"audit_ns"}, nil))
+
+			schema := iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64, Required: true})
+			tableIdent := table.Identifier{"audit_ns", "audited"}
+			_, err = restCatalog.CreateTable(ctx, tableIdent, schema)
+			require.NoError(t, err)
+
+			_, err = restCatalog.UpdateTable(ctx, tableIdent, nil, []table.Update{
+				table.NewSetPropertiesUpdate(iceberg.Properties{"owner": "alice"}),
+			})
+			require.NoError(t, err)
+
+			renamedIdent := table.Identifier{"audit_ns", "renamed"}
+			_, err = restCatalog.RenameTable(ctx, tableIdent, renamedIdent)
+			require.NoError(t, err)
+			_, err = restCatalog.RenameTable(ctx, renamedIdent, tableIdent)
+			require.NoError(t, err)
+
+			require.NoError(t, restCatalog.DropTable(ctx, tableIdent))
+			assert.Error(t, restCatalog.DropTable(ctx, tableIdent))
+
+			events, err := sink.Query(ctx, audit.Filter{Namespace: []string{"audit_ns"}, Table: "audited"})
+			require.NoError(t, err)
+			require.Len(t, events, 6, "renames match their source and their destination")
+
+			failedDrop, drop, rename, update, create := events[0], events[1], events[3], events[4], events[5]
+			assert.Equal(t, audit.OpCreateTable, create.Operation)
+			assert.NotEmpty(t, create.NewMetadataLocation)
+
+			assert.Equal(t, audit.OpUpdateTable, update.Operation)
+			assert.Equal(t, []string{"set-properties"}, update.Updates)
+			assert.Equal(t, create.NewMetadataLocation, update.OldMetadataLocation)
+			assert.NotEqual(t, update.OldMetadataLocation, update.NewMetadataLocation)
+
+			assert.Equal(t, audit.OpRenameTable, rename.Operation)
+			assert.Equal(t, []string{"audit_ns"}, rename.ToNamespace)
+			assert.Equal(t, "renamed", rename.ToTable)
+			assert.Equal(t, update.NewMetadataLocation, rename.OldMetadataLocation)
+			assert.Empty(t, rename.NewMetadataLocation, "a rename writes no metadata")
+
+			assert.Equal(t, audit.OpDropTable, drop.Operation)
+			assert.Equal(t, "alice", drop.Principal)
+			assert.NotEmpty(t, drop.RequestID)
+			assert.Equal(t, update.NewMetadataLocation, drop.OldMetadataLocation)
+			assert.Equal(t, audit.OutcomeSuccess, drop.Outcome)
+
+			assert.Equal(t, audit.OpDropTable, failedDrop.Operation)
+			assert.Equal(t, audit.OutcomeFailure, failedDrop.Outcome)
+			assert.Equal(t, http.StatusNotFound, failedDrop.Status)
+
+			query := url.Values{"namespace": {"audit_ns"}, "table": {"audited"}, "limit": {"2"}}
+			resp, err := http.Get(server.URL + "/audit/events?" + query.Encode())
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "the audit log requires the admin token")
+
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/audit/events?"+query.Encode(), nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer auditor-token")
+			resp, err = http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var body handlers.ListAuditEventsResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			require.Len(t, body.Events, 2)
+			assert.Equal(t, failedDrop.RequestID, body.Events[0].RequestID)
+
+			// A client that gave up on the response still leaves its event.
+			cancelled, cancel := context.WithCancel(context.Background())
+			cancel()
+			req = httptest.NewRequest(http.MethodPost, "/v1/namespaces/audit_ns/properties",
+				strings.NewReader(`{"updates": {"owner": "bob"}}`)).WithContext(cancelled)
+			engine.ServeHTTP(httptest.NewRecorder(), req)
+
+			events, err = sink.Query(ctx, audit.Filter{Namespace: []string{"audit_ns"}, Limit: 1})
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, audit.OpUpdateProperties, events[0].Operation)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/cache"
+)
+
+// countingCatalog counts backend table loads.
+type countingCatalog struct {
+	catalog.Catalog
+	loads atomic.Int32
+}
+
+func (c *countingCatalog) LoadTable(ctx context.Context, identifier table.Identifier, props iceberg.Properties) (*table.Table, error) {
+	c.loads.Add(1)
+	return c.Catalog.LoadTable(ctx, identifier, props)
+}
+
+func TestMetadataCache(t *testing.T) {
+	backendCatalog := newSQLCatalog(t)
+
+	backend := &countingCatalog{Catalog: backendCatalog}
+	cached := cache.New(backend, cache.Config{Enabled: true, TTL: time.Minute, MaxEntries: 2})
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	router.Setup(engine, handlers.NewCatalogHandler(cached, handlers.Config{},
+		handlers.WithMetadataEncoder(cached),
+	))
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	restCatalog, err := rest.NewCatalog(context.Background(), "test-client", server.URL)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	schema := iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64})
+	require.NoError(t, restCatalog.CreateNamespace(ctx, table.Identifier{"cache_ns"}, nil))
+	for _, name := range []string{"a", "b", "c"} {
+		_, err = restCatalog.CreateTable(ctx, table.Identifier{"cache_ns", name}, schema)
+		require.NoError(t, err)
+	}
+	tableIdent := table.Identifier{"cache_ns", "a"}
+
+	t.Run("Hit", func(t *testing.T) {
+		backend.loads.Store(0)
+		first, err := restCatalog.LoadTable(ctx, tableIdent, nil)
+		require.NoError(t, err)
+		second, err := restCatalog.LoadTable(ctx, tableIdent, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(1), backend.loads.Load())
+		assert.Equal(t, first.MetadataLocation(), second.MetadataLocation())
+		assert.True(t, first.Metadata().Equals(second.Metadata()))
+	})
+
+	t.Run("InvalidatedOnCommit", func(t *testing.T) {
+		before, err := restCatalog.LoadTable(ctx, tableIdent, nil)
+		require.NoError(t, err)
+
+		backend.loads.Store(0)
+		_, err = restCatalog.UpdateTable(ctx, tableIdent, nil,
+			[]table.Update{table.NewSetPropertiesUpdate(iceberg.Properties{"owner": "cache"})})
+		require.NoError(t, err)
+
+		after, err := restCatalog.LoadTable(ctx, tableIdent, nil)
+		require.NoError(t, err)
+		assert.NotEqual(t, before.MetadataLocation(), after.MetadataLocation())
+		assert.Equal(t, "cache", after.Properties()["owner"])
+		assert.Equal(t, int32(0), backend.loads.Load(), "the commit doesn't load the table and keeps the result cached")
+	})
+
+	t.Run("Eviction", func(t *testing.T) {
+		for _, name := range []string{"a", "b", "c"} {
+			_, err := restCatalog.LoadTable(ctx, table.Identifier{"cache_ns", name}, nil)
+			require.NoError(t, err)
+		}
+
+		// "a" was least recently used and fell out of the two entry cache.
+		backend.loads.Store(0)
+		_, err := restCatalog.LoadTable(ctx, tableIdent, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), backend.loads.Load())
+	})
+
+	t.Run("InvalidatedOnDrop", func(t *testing.T) {
+		_, err := restCatalog.LoadTable(ctx, tableIdent, nil)
+		require.NoError(t, err)
+		require.NoError(t, restCatalog.DropTable(ctx, tableIdent))
+
+		_, err = restCatalog.LoadTable(ctx, tableIdent, nil)
+		assert.ErrorIs(t, err, catalog.ErrNoSuchTable)
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		short := cache.New(backend, cache.Config{Enabled: true, TTL: 10 * time.Millisecond})
+		ident := table.Identifier{"cache_ns", "b"}
+
+		backend.loads.Store(0)
+		_, err := short.LoadTable(ctx, ident, nil)
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+		_, err = short.LoadTable(ctx, ident, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), backend.loads.Load())
+	})
+}
@@ -0,0 +1,238 @@
+package test
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/coalesce"
+)
+
+// gatedCatalog blocks reads until release is closed and counts backend calls.
+type gatedCatalog struct {
+	catalog.Catalog
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (c *gatedCatalog) wait(ctx context.Context) error {
+	c.calls.Add(1)
+	select {
+	case <-c.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *gatedCatalog) LoadTable(ctx context.Context, identifier table.Identifier, props iceberg.Properties) (*table.Table, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.Catalog.LoadTable(ctx, identifier, props)
+}
+
+func (c *gatedCatalog) LoadNamespaceProperties(ctx context.Context, namespace table.Identifier) (iceberg.Properties, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.Catalog.LoadNamespaceProperties(ctx, namespace)
+}
+
+func (c *gatedCatalog) ListTables(ctx context.Context, namespace table.Identifier) iter.Seq2[table.Identifier, error] {
+	return func(yield func(table.Identifier, error) bool) {
+		if err := c.wait(ctx); err != nil {
+			yield(nil, err)
+			return
+		}
+		for ident, err := range c.Catalog.ListTables(ctx, namespace) {
+			if !yield(ident, err) {
+				return
+			}
+		}
+	}
+}
+
+func TestCoalescedReads(t *testing.T) {
+	backendCatalog := newSQLCatalog(t)
+
+	ctx := context.Background()
+	ns := table.Identifier{"coalesce_ns"}
+	tableIdent := table.Identifier{"coalesce_ns", "tbl"}
+	require.NoError(t, backendCatalog.CreateNamespace(ctx, ns, iceberg.Properties{"owner": "jobs"}))
+	_, err := backendCatalog.CreateTable(ctx, tableIdent,
+		iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64}))
+	require.NoError(t, err)
+
+	// run starts n concurrent reads and releases the backend once all of them
+	// are waiting on it.
+	run := func(t *testing.T, n int, read func(context.Context, *coalesce.Catalog) error) *gatedCatalog {
+		gated := &gatedCatalog{Catalog: backendCatalog, release: make(chan struct{})}
+		cat := coalesce.New(gated, coalesce.Config{})
+
+		var wg sync.WaitGroup
+		errs := make(chan error, n)
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- read(ctx, cat)
+			}()
+		}
+
+		require.Eventually(t, func() bool { return gated.calls.Load() >= 1 }, time.Second, time.Millisecond)
+		// Give the remaining readers time to join the in-flight call.
+		time.Sleep(50 * time.Millisecond)
+		close(gated.release)
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		return gated
+	}
+
+	t.Run("LoadTable", func(t *testing.T) {
+		gated := run(t, 50, func(ctx context.Context, cat *coalesce.Catalog) error {
+			tbl, err := cat.LoadTable(ctx, tableIdent, nil)
+			if err == nil {
+				assert.Equal(t, tableIdent, tbl.Identifier())
+			}
+			return err
+		})
+		assert.Equal(t, int32(1), gated.calls.Load())
+	})
+
+	t.Run("LoadNamespaceProperties", func(t *testing.T) {
+		gated := run(t, 20, func(ctx context.Context, cat *coalesce.Catalog) error {
+			props, err := cat.LoadNamespaceProperties(ctx, ns)
+			if err == nil {
+				assert.Equal(t, "jobs", props["owner"])
+			}
+			return err
+		})
+		assert.Equal(t, int32(1), gated.calls.Load())
+	})
+
+	t.Run("ListTables", func(t *testing.T) {
+		gated := run(t, 20, func(ctx context.Context, cat *coalesce.Catalog) error {
+			var idents []table.Identifier
+			for ident, err := range cat.ListTables(ctx, ns) {
+				if err != nil {
+					return err
+				}
+				idents = append(idents, ident)
+			}
+			assert.Equal(t, []table.Identifier{tableIdent}, idents)
+			return nil
+		})
+		assert.Equal(t, int32(1), gated.calls.Load())
+	})
+
+	t.Run("ListTablesAfterCreate", func(t *testing.T) {
+		gated := &gatedCatalog{Catalog: backendCatalog, release: make(chan struct{})}
+		cat := coalesce.New(gated, coalesce.Config{})
+		list := func(out chan<- []table.Identifier) {
+			var idents []table.Identifier
+			for ident, err := range cat.ListTables(ctx, ns) {
+				assert.NoError(t, err)
+				idents = append(idents, ident)
+			}
+			out <- idents
+		}
+
+		// A listing that started before the create.
+		before := make(chan []table.Identifier, 1)
+		go list(before)
+		require.Eventually(t, func() bool { return gated.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+		created := table.Identifier{"coalesce_ns", "created"}
+		_, err := cat.CreateTable(ctx, created,
+			iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64}))
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, backendCatalog.DropTable(ctx, created)) })
+
+		after := make(chan []table.Identifier, 1)
+		go list(after)
+		require.Eventually(t, func() bool { return gated.calls.Load() == 2 }, time.Second, time.Millisecond,
+			"a listing after the create doesn't join the one from before it")
+
+		close(gated.release)
+		<-before
+		assert.Contains(t, <-after, created)
+	})
+
+	t.Run("ReadYourWrites", func(t *testing.T) {
+		gated := &gatedCatalog{Catalog: backendCatalog, release: make(chan struct{})}
+		cat := coalesce.New(gated, coalesce.Config{})
+
+		// A load that started before the commit.
+		before := make(chan *table.Table, 1)
+		go func() {
+			tbl, err := cat.LoadTable(ctx, tableIdent, nil)
+			assert.NoError(t, err)
+			before <- tbl
+		}()
+		require.Eventually(t, func() bool { return gated.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+		base, err := backendCatalog.LoadTable(ctx, tableIdent, nil)
+		require.NoError(t, err)
+		_, location, err := cat.CommitTable(ctx, base, nil, []table.Update{
+			table.NewSetPropertiesUpdate(iceberg.Properties{"written": "yes"}),
+		})
+		require.NoError(t, err)
+
+		after := make(chan *table.Table, 1)
+		go func() {
+			tbl, err := cat.LoadTable(ctx, tableIdent, nil)
+			assert.NoError(t, err)
+			after <- tbl
+		}()
+		require.Eventually(t, func() bool { return gated.calls.Load() == 2 }, time.Second, time.Millisecond,
+			"a load after the commit doesn't join the one from before it")
+
+		close(gated.release)
+		<-before
+		assert.Equal(t, location, (<-after).MetadataLocation())
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		gated := &gatedCatalog{Catalog: backendCatalog, release: make(chan struct{})}
+		cat := coalesce.New(gated, coalesce.Config{Timeout: 20 * time.Millisecond})
+		defer close(gated.release)
+
+		_, err := cat.LoadTable(ctx, tableIdent, nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "a stuck shared call doesn't run forever")
+	})
+
+	t.Run("Cancellation", func(t *testing.T) {
+		gated := &gatedCatalog{Catalog: backendCatalog, release: make(chan struct{})}
+		cat := coalesce.New(gated, coalesce.Config{})
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := cat.LoadTable(ctx, tableIdent, nil)
+			done <- err
+		}()
+		require.Eventually(t, func() bool { return gated.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+		// A caller that gives up returns right away without failing the
+		// caller that started the shared load.
+		cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err := cat.LoadTable(cancelled, tableIdent, nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		close(gated.release)
+		assert.NoError(t, <-done)
+		assert.Equal(t, int32(1), gated.calls.Load())
+	})
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/cache"
+)
+
+// BenchmarkCommit measures sequential commits to one table on a file backed
+// SQLite catalog, without and with the metadata cache in front of it.
+// handler-loads/commit counts the table loads outside the backend's own
+// CommitTable.
+//
+//	go test ./test -run '^$' -bench BenchmarkCommit -benchtime 500x
+func BenchmarkCommit(b *testing.B) {
+	for _, bc := range []struct {
+		name   string
+		cached bool
+	}{
+		{"Uncached", false},
+		{"Cached", true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			backendCatalog := newSQLCatalog(b)
+
+			backend := &countingCatalog{Catalog: backendCatalog}
+			var (
+				cat  catalog.Catalog = backend
+				opts []handlers.Option
+			)
+			if bc.cached {
+				cached := cache.New(backend, cache.Config{Enabled: true, TTL: time.Minute, MaxEntries: 100})
+				cat = cached
+				opts = append(opts, handlers.WithMetadataEncoder(cached))
+			}
+
+			gin.SetMode(gin.ReleaseMode)
+			engine := gin.New()
+			router.Setup(engine, handlers.NewCatalogHandler(cat, handlers.Config{}, opts...))
+			server := httptest.NewServer(engine)
+			defer server.Close()
+
+			restCatalog, err := rest.NewCatalog(context.Background(), "bench-client", server.URL)
+			require.NoError(b, err)
+
+			ctx := context.Background()
+			tableIdent := table.Identifier{"bench_ns", "commits"}
+			require.NoError(b, restCatalog.CreateNamespace(ctx, table.Identifier{"bench_ns"}, nil))
+			_, err = restCatalog.CreateTable(ctx, tableIdent,
+				iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64}))
+			require.NoError(b, err)
+
+			backend.loads.Store(0)
+			commits := 0
+			for b.Loop() {
+				_, err := restCatalog.UpdateTable(ctx, tableIdent, nil,
+					[]table.Update{table.NewSetPropertiesUpdate(iceberg.Properties{"n": strconv.Itoa(commits)})})
+				require.NoError(b, err)
+				commits++
+			}
+
+			b.ReportMetric(float64(commits)/b.Elapsed().Seconds(), "commits/s")
+			b.ReportMetric(float64(backend.loads.Load())/float64(commits), "handler-loads/commit")
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/phases"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/cache"
+	"github.com/xixipi-lining/iceberg-rest-catalog/commit"
+)
+
+// heldCatalog holds every commit until release is closed and tracks how many
+// commits of a table run at once.
+type heldCatalog struct {
+	catalog.Catalog
+	release chan struct{}
+	entered chan string
+
+	mu       sync.Mutex
+	active   map[string]int
+	maxByKey map[string]int
+}
+
+func newHeldCatalog(backend catalog.Catalog) *heldCatalog {
+	return &heldCatalog{
+		Catalog:  backend,
+		release:  make(chan struct{}),
+		entered:  make(chan string, 64),
+		active:   make(map[string]int),
+		maxByKey: make(map[string]int),
+	}
+}
+
+func (c *heldCatalog) CommitTable(ctx context.Context, tbl *table.Table, reqs []table.Requirement, updates []table.Update) (table.Metadata, string, error) {
+	key := strings.Join(tbl.Identifier(), ".")
+	c.mu.Lock()
+	c.active[key]++
+	c.maxByKey[key] = max(c.maxByKey[key], c.active[key])
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.active[key]--
+		c.mu.Unlock()
+	}()
+
+	c.entered <- key
+	<-c.release
+	return c.Catalog.CommitTable(ctx, tbl, reqs, updates)
+}
+
+// queueResponses records the commit batch header and the phases of each
+// commit served.
+type queueResponses struct {
+	mu      sync.Mutex
+	batches []string
+	phases  []map[string]time.Duration
+}
+
+func TestCommitQueue(t *testing.T) {
+	// setup serves a held SQLite catalog through a commit queue, behind the
+	// metadata cache if cached is set.
+	setup := func(t *testing.T, cfg commit.QueueConfig, cached bool) (*heldCatalog, *rest.Catalog, *queueResponses) {
+		backendCatalog := newSQLCatalog(t)
+		held := newHeldCatalog(backendCatalog)
+		var served catalog.Catalog = held
+		var opts []handlers.Option
+		if cached {
+			c := cache.New(held, cache.Config{Enabled: true, TTL: time.Minute})
+			served = c
+			opts = append(opts, handlers.WithMetadataEncoder(c))
+		}
+
+		responses := &queueResponses{}
+		gin.SetMode(gin.TestMode)
+		engine := gin.New()
+		engine.Use(func(c *gin.Context) {
+			timings := phases.Track(c)
+			c.Next()
+			responses.mu.Lock()
+			defer responses.mu.Unlock()
+			if b := c.Writer.Header().Get(handlers.CommitBatchHeader); b != "" {
+				responses.batches = append(responses.batches, b)
+			}
+			if c.Request.Method == http.MethodPost && c.FullPath() == "/v1/namespaces/:namespace/tables/:table" {
+				responses.phases = append(responses.phases, timings.Durations())
+			}
+		})
+		router.Setup(engine, handlers.NewCatalogHandler(served, handlers.Config{},
+			append(opts, handlers.WithCommitQueue(cfg))...,
+		))
+		server := httptest.NewServer(engine)
+		t.Cleanup(server.Close)
+
+		restCatalog, err := rest.NewCatalog(context.Background(), "test-client", server.URL)
+		require.NoError(t, err)
+		require.NoError(t, restCatalog.CreateNamespace(context.Background(), table.Identifier{"queue_ns"}, nil))
+		return held, restCatalog, responses
+	}
+
+	schema := iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64, Required: true})
+	createTable := func(t *testing.T, restCatalog *rest.Catalog, name string) table.Identifier {
+		ident := table.Identifier{"queue_ns", name}
+		_, err := restCatalog.CreateTable(context.Background(), ident, schema)
+		require.NoError(t, err)
+		return ident
+	}
+	setProperty := func(restCatalog *rest.Catalog, ident table.Identifier, key string) error {
+		_, err := restCatalog.UpdateTable(context.Background(), ident, nil,
+			[]table.Update{table.NewSetPropertiesUpdate(iceberg.Properties{key: "true"})})
+		return err
+	}
+	// expectNoCommit fails if another backend commit starts within a short
+	// grace period.
+	expectNoCommit := func(t *testing.T, held *heldCatalog) {
+		select {
+		case key := <-held.entered:
+			t.Fatalf("unexpected concurrent commit to %s", key)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	t.Run("Serialized", func(t *testing.T) {
+		held, restCatalog, _ := setup(t, commit.QueueConfig{Enabled: true}, false)
+		first := createTable(t, restCatalog, "first")
+		second := createTable(t, restCatalog, "second")
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 4)
+		for i := range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- setProperty(restCatalog, first, fmt.Sprintf("writer-%d", i))
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- setProperty(restCatalog, second, "writer")
+		}()
+
+		// Both tables commit in parallel, each with one commit at a time.
+		started := map[string]bool{<-held.entered: true, <-held.entered: true}
+		assert.Equal(t, map[string]bool{"queue_ns.first": true, "queue_ns.second": true}, started)
+		expectNoCommit(t, held)
+
+		close(held.release)
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, 1, held.maxByKey["queue_ns.first"])
+
+		tbl, err := restCatalog.LoadTable(context.Background(), first, nil)
+		require.NoError(t, err)
+		for i := range 3 {
+			assert.Equal(t, "true", tbl.Properties()[fmt.Sprintf("writer-%d", i)])
+		}
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		held, restCatalog, responses := setup(t, commit.QueueConfig{Enabled: true, Depth: 1}, false)
+		ident := createTable(t, restCatalog, "full")
+
+		errs := make(chan error, 2)
+		go func() { errs <- setProperty(restCatalog, ident, "in-progress") }()
+		<-held.entered
+		go func() { errs <- setProperty(restCatalog, ident, "waiting") }()
+
+		require.Eventually(t, func() bool {
+			return strings.Contains(scrapeMetrics(t), "iceberg_rest_commit_queue_waiting 1\n")
+		}, time.Second, time.Millisecond)
+
+		err := setProperty(restCatalog, ident, "rejected")
+		assert.ErrorIs(t, err, rest.ErrServiceUnavailable)
+
+		time.Sleep(100 * time.Millisecond)
+		close(held.release)
+		assert.NoError(t, <-errs)
+		assert.NoError(t, <-errs)
+
+		// The waiting commit reports its turn as queue time, not as time
+		// spent in the backend.
+		responses.mu.Lock()
+		defer responses.mu.Unlock()
+		var waited map[string]time.Duration
+		for _, durations := range responses.phases {
+			if durations[phases.Queue] >= 100*time.Millisecond {
+				waited = durations
+			}
+		}
+		require.NotNil(t, waited, "%v", responses.phases)
+		assert.Less(t, waited[phases.Backend], waited[phases.Queue])
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		held, restCatalog, _ := setup(t, commit.QueueConfig{Enabled: true, Timeout: 50 * time.Millisecond}, false)
+		ident := createTable(t, restCatalog, "slow")
+
+		errs := make(chan error, 1)
+		go func() { errs <- setProperty(restCatalog, ident, "in-progress") }()
+		<-held.entered
+
+		err := setProperty(restCatalog, ident, "late")
+		assert.ErrorIs(t, err, rest.ErrServiceUnavailable)
+
+		close(held.release)
+		assert.NoError(t, <-errs)
+
+		tbl, err := restCatalog.LoadTable(context.Background(), ident, nil)
+		require.NoError(t, err)
+		assert.NotContains(t, tbl.Properties(), "late")
+	})
+
+	// batched appends to a table from four writers at once, three of which
+	// wait for the first commit and are then committed as one batch.
+	batched := func(t *testing.T, cached bool) {
+		held, restCatalog, responses := setup(t, commit.QueueConfig{Enabled: true, MaxBatch: 8}, cached)
+		ctx := context.Background()
+		ident := createTable(t, restCatalog, "batched")
+
+		arrowSchema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+		rows := func(id int64) arrow.Table {
+			b := array.NewRecordBuilder(memory.DefaultAllocator, arrowSchema)
+			defer b.Release()
+			b.Field(0).(*array.Int64Builder).Append(id)
+			rec := b.NewRecord()
+			defer rec.Release()
+			return array.NewTableFromRecords(arrowSchema, []arrow.Record{rec})
+		}
+
+		// Every writer starts from the same snapshot, as streaming writers
+		// committing at the same moment do.
+		writers := make([]*table.Table, 4)
+		for i := range writers {
+			tbl, err := restCatalog.LoadTable(ctx, ident, nil)
+			require.NoError(t, err)
+			writers[i] = tbl
+		}
+
+		errs := make(chan error, len(writers))
+		go func() {
+			_, err := writers[0].AppendTable(ctx, rows(0), 10, nil)
+			errs <- err
+		}()
+		<-held.entered
+
+		var waiting atomic.Int32
+		for i, w := range writers[1:] {
+			go func() {
+				waiting.Add(1)
+				_, err := w.AppendTable(ctx, rows(int64(i+1)), 10, nil)
+				errs <- err
+			}()
+		}
+		require.Eventually(t, func() bool { return waiting.Load() == 3 }, time.Second, time.Millisecond)
+		// Let the stragglers reach the queue before the first commit ends.
+		time.Sleep(100 * time.Millisecond)
+
+		close(held.release)
+		for range writers {
+			assert.NoError(t, <-errs)
+		}
+		assert.Contains(t, responses.batches, "3")
+
+		tbl, err := restCatalog.LoadTable(ctx, ident, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "4", tbl.CurrentSnapshot().Summary.Properties["total-records"])
+		assert.Len(t, tbl.Metadata().Snapshots(), 4)
+
+		result, err := tbl.Scan().ToArrowTable(ctx)
+		require.NoError(t, err)
+		defer result.Release()
+		assert.Equal(t, int64(4), result.NumRows())
+	}
+
+	t.Run("Batched", func(t *testing.T) { batched(t, false) })
+	// The batch is rebased onto the table the cache kept from the first
+	// commit, which was made on an unloaded base.
+	t.Run("BatchedCached", func(t *testing.T) { batched(t, true) })
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/commit"
+)
+
+func TestCommitRetry(t *testing.T) {
+	setup := func(t *testing.T, retry commit.Config) (*rest.Catalog, *[]string) {
+		backendCatalog := newSQLCatalog(t)
+
+		var attempts []string
+		gin.SetMode(gin.TestMode)
+		engine := gin.New()
+		engine.Use(func(c *gin.Context) {
+			c.Next()
+			if a := c.Writer.Header().Get(handlers.CommitAttemptsHeader); a != "" {
+				attempts = append(attempts, a)
+			}
+		})
+		router.Setup(engine, handlers.NewCatalogHandler(backendCatalog, handlers.Config{},
+			handlers.WithCommitRetry(retry),
+		))
+		server := httptest.NewServer(engine)
+		t.Cleanup(server.Close)
+
+		restCatalog, err := rest.NewCatalog(context.Background(), "test-client", server.URL)
+		require.NoError(t, err)
+		return restCatalog, &attempts
+	}
+
+	schema := iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64, Required: true})
+	rows := func(ids ...int64) arrow.Table {
+		arrowSchema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+		b := array.NewRecordBuilder(memory.DefaultAllocator, arrowSchema)
+		defer b.Release()
+		b.Field(0).(*array.Int64Builder).AppendValues(ids, nil)
+		rec := b.NewRecord()
+		defer rec.Release()
+		return array.NewTableFromRecords(arrowSchema, []arrow.Record{rec})
+	}
+
+	// race loads the table twice and appends through both copies, so that
+	// the second append is based on a stale snapshot.
+	race := func(t *testing.T, restCatalog *rest.Catalog, name string) (*table.Table, error) {
+		ctx := context.Background()
+		ident := table.Identifier{"retry_ns", name}
+		_, err := restCatalog.CreateTable(ctx, ident, schema)
+		require.NoError(t, err)
+
+		first, err := restCatalog.LoadTable(ctx, ident, nil)
+		require.NoError(t, err)
+		second, err := restCatalog.LoadTable(ctx, ident, nil)
+		require.NoError(t, err)
+
+		_, err = first.AppendTable(ctx, rows(1, 2), 10, nil)
+		require.NoError(t, err)
+
+		return second.AppendTable(ctx, rows(4, 5, 6), 10, nil)
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		restCatalog, attempts := setup(t, commit.Config{})
+		require.NoError(t, restCatalog.CreateNamespace(context.Background(), table.Identifier{"retry_ns"}, nil))
+
+		_, err := race(t, restCatalog, "plain")
+		assert.ErrorIs(t, err, rest.ErrCommitFailed)
+		assert.Empty(t, *attempts)
+	})
+
+	t.Run("RebasedAppend", func(t *testing.T) {
+		restCatalog, attempts := setup(t, commit.Config{Enabled: true, MaxAttempts: 3})
+		ctx := context.Background()
+		require.NoError(t, restCatalog.CreateNamespace(ctx, table.Identifier{"retry_ns"}, nil))
+
+		tbl, err := race(t, restCatalog, "appends")
+		require.NoError(t, err)
+		assert.Equal(t, "2", (*attempts)[len(*attempts)-1])
+
+		current, err := restCatalog.LoadTable(ctx, tbl.Identifier(), nil)
+		require.NoError(t, err)
+		snapshot := current.CurrentSnapshot()
+		require.NotNil(t, snapshot)
+		assert.Equal(t, int64(2), snapshot.SequenceNumber)
+		assert.Equal(t, "5", snapshot.Summary.Properties["total-records"])
+		require.NotNil(t, snapshot.ParentSnapshotID)
+
+		result, err := current.Scan().ToArrowTable(ctx)
+		require.NoError(t, err)
+		defer result.Release()
+		assert.Equal(t, int64(5), result.NumRows(), "both appends are visible")
+	})
+
+	t.Run("RequirementStillFails", func(t *testing.T) {
+		restCatalog, attempts := setup(t, commit.Config{Enabled: true, MaxAttempts: 3})
+		ctx := context.Background()
+		require.NoError(t, restCatalog.CreateNamespace(ctx, table.Identifier{"retry_ns"}, nil))
+		ident := table.Identifier{"retry_ns", "props"}
+		_, err := restCatalog.CreateTable(ctx, ident, schema)
+		require.NoError(t, err)
+
+		_, err = restCatalog.UpdateTable(ctx, ident,
+			[]table.Requirement{table.AssertCurrentSchemaID(42)},
+			[]table.Update{table.NewSetPropertiesUpdate(iceberg.Properties{"a": "b"})})
+		assert.ErrorIs(t, err, rest.ErrCommitFailed)
+		assert.Equal(t, "1", (*attempts)[len(*attempts)-1])
+
+		_, err = restCatalog.UpdateTable(ctx, ident, nil,
+			[]table.Update{table.NewSetPropertiesUpdate(iceberg.Properties{"attempt": strconv.Itoa(1)})})
+		require.NoError(t, err)
+	})
+}
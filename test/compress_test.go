@@ -0,0 +1,199 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+)
+
+func TestCompression(t *testing.T) {
+	backendCatalog := newSQLCatalog(t)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.Compression(middleware.CompressionConfig{
+		Enabled:        true,
+		MinSize:        1024,
+		MaxRequestSize: 1 << 20,
+	}))
+	router.Setup(engine, handlers.NewCatalogHandler(backendCatalog, handlers.Config{}))
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	restCatalog, err := rest.NewCatalog(context.Background(), "test-client", server.URL)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, restCatalog.CreateNamespace(ctx, table.Identifier{"compress_ns"}, nil))
+	fields := make([]iceberg.NestedField, 50)
+	for i := range fields {
+		fields[i] = iceberg.NestedField{ID: i + 1, Name: fmt.Sprintf("column_%d", i), Type: iceberg.PrimitiveTypes.String}
+	}
+	_, err = restCatalog.CreateTable(ctx, table.Identifier{"compress_ns", "wide"}, iceberg.NewSchema(0, fields...))
+	require.NoError(t, err)
+	tableURL := server.URL + "/v1/namespaces/compress_ns/tables/wide"
+
+	// The default transport asks for gzip and decodes it transparently, so
+	// these requests set Accept-Encoding themselves.
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	get := func(method, url, acceptEncoding string) (*http.Response, []byte) {
+		req, err := http.NewRequest(method, url, nil)
+		require.NoError(t, err)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, body
+	}
+	assertLoadTableResponse := func(t *testing.T, body []byte) {
+		var loaded struct {
+			MetadataLocation string `json:"metadata-location"`
+		}
+		require.NoError(t, json.Unmarshal(body, &loaded))
+		assert.NotEmpty(t, loaded.MetadataLocation)
+	}
+
+	t.Run("Zstd", func(t *testing.T) {
+		resp, body := get(http.MethodGet, tableURL, "gzip;q=0.5, zstd")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "zstd", resp.Header.Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+
+		dec, err := zstd.NewReader(nil)
+		require.NoError(t, err)
+		defer dec.Close()
+		plain, err := dec.DecodeAll(body, nil)
+		require.NoError(t, err)
+		assert.Greater(t, len(plain), len(body))
+		assertLoadTableResponse(t, plain)
+	})
+
+	t.Run("Gzip", func(t *testing.T) {
+		resp, body := get(http.MethodGet, tableURL, "gzip, zstd;q=0")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		plain, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assertLoadTableResponse(t, plain)
+	})
+
+	t.Run("Skipped", func(t *testing.T) {
+		resp, body := get(http.MethodGet, tableURL, "")
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+		assertLoadTableResponse(t, body)
+
+		resp, body = get(http.MethodGet, server.URL+"/v1/config", "zstd, gzip")
+		assert.Empty(t, resp.Header.Get("Content-Encoding"), "below the size threshold")
+		assert.JSONEq(t, `{"defaults": null, "overrides": null}`, string(body))
+
+		resp, _ = get(http.MethodHead, tableURL, "zstd, gzip")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+
+		resp, _ = get(http.MethodGet, tableURL, "br")
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	})
+
+	t.Run("RequestBody", func(t *testing.T) {
+		update := []byte(`{"requirements": [], "updates": [{"action": "set-properties", "updates": {"compressed": "true"}}]}`)
+		post := func(encoding string, body []byte) *http.Response {
+			req, err := http.NewRequest(http.MethodPost, tableURL, bytes.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Encoding", encoding)
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			return resp
+		}
+
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		_, err := gw.Write(update)
+		require.NoError(t, err)
+		require.NoError(t, gw.Close())
+		assert.Equal(t, http.StatusOK, post("gzip", buf.Bytes()).StatusCode)
+
+		enc, err := zstd.NewWriter(nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, post("zstd", enc.EncodeAll(update, nil)).StatusCode)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, post("br", update).StatusCode)
+		assert.Equal(t, http.StatusBadRequest, post("gzip", update).StatusCode)
+
+		// A small body that decompresses past max-request-size.
+		large := []byte(`{"requirements": [], "updates": [{"action": "set-properties", "updates": {"padding": "` +
+			strings.Repeat("x", 2<<20) + `"}}]}`)
+		resp := post("zstd", enc.EncodeAll(large, nil))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		assert.True(t, resp.Close, "the rest of the body is not read")
+
+		// A frame asking for a 1 GiB window: magic number, no flags, window
+		// descriptor, then the body as one raw block.
+		frame := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00, 20 << 3}
+		blockHeader := len(update)<<3 | 1
+		frame = append(frame, byte(blockHeader), byte(blockHeader>>8), byte(blockHeader>>16))
+		frame = append(frame, update...)
+		assert.Equal(t, http.StatusBadRequest, post("zstd", frame).StatusCode)
+
+		tbl, err := restCatalog.LoadTable(ctx, table.Identifier{"compress_ns", "wide"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "true", tbl.Properties()["compressed"])
+	})
+
+	t.Run("RequestDecoderClosed", func(t *testing.T) {
+		// Keeps the decoded body of the last request.
+		bodies := make(chan io.ReadCloser, 1)
+		engine := gin.New()
+		engine.Use(middleware.Compression(middleware.CompressionConfig{Enabled: true}))
+		engine.POST("/echo", func(c *gin.Context) {
+			data, err := io.ReadAll(c.Request.Body)
+			assert.NoError(t, err)
+			bodies <- c.Request.Body
+			c.Data(http.StatusOK, "text/plain", data)
+		})
+		echo := httptest.NewServer(engine)
+		defer echo.Close()
+
+		enc, err := zstd.NewWriter(nil)
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, echo.URL+"/echo", bytes.NewReader(enc.EncodeAll([]byte("hello"), nil)))
+		require.NoError(t, err)
+		req.Header.Set("Content-Encoding", "zstd")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(data))
+
+		_, err = (<-bodies).Read(make([]byte, 1))
+		assert.ErrorIs(t, err, zstd.ErrDecoderClosed, "the decoder is closed once the request is done")
+	})
+}
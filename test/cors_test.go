@@ -0,0 +1,86 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+)
+
+func TestCORS(t *testing.T) {
+	corsMiddleware, err := middleware.CORS(middleware.CORSConfig{
+		Enabled:          true,
+		AllowOrigins:     []string{"https://ui.example.com", "https://*.corp.example.com"},
+		AllowHeaders:     []string{"Authorization", "Content-Type"},
+		ExposeHeaders:    []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(corsMiddleware)
+	router.Setup(engine, handlers.NewCatalogHandler(nil, handlers.Config{}))
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	do := func(method, origin string, header map[string]string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+"/v1/config", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("AllowedOrigin", func(t *testing.T) {
+		for _, origin := range []string{"https://ui.example.com", "https://team.corp.example.com"} {
+			resp := do(http.MethodGet, origin, nil)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, origin, resp.Header.Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+			assert.Equal(t, "X-Request-Id", resp.Header.Get("Access-Control-Expose-Headers"))
+		}
+	})
+
+	t.Run("Preflight", func(t *testing.T) {
+		resp := do(http.MethodOptions, "https://ui.example.com", map[string]string{
+			"Access-Control-Request-Method":  http.MethodPost,
+			"Access-Control-Request-Headers": "Authorization",
+		})
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, "https://ui.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Contains(t, resp.Header.Get("Access-Control-Allow-Methods"), http.MethodPost)
+		assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "Authorization")
+		assert.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("RejectedOrigin", func(t *testing.T) {
+		resp := do(http.MethodGet, "https://evil.example.org", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+
+		resp = do(http.MethodOptions, "https://evil.example.org", map[string]string{
+			"Access-Control-Request-Method": http.MethodDelete,
+		})
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		_, err := middleware.CORS(middleware.CORSConfig{Enabled: true, AllowCredentials: true})
+		assert.ErrorContains(t, err, "allow-credentials requires explicit allow-origins")
+	})
+}
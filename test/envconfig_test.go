@@ -0,0 +1,82 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/admin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/server"
+	"github.com/xixipi-lining/iceberg-rest-catalog/envconfig"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+)
+
+// envTestConfig mirrors the shape of the server config.
+type envTestConfig struct {
+	DefaultCatalog string                        `yaml:"default-catalog"`
+	Catalogs       map[string]iceberg.Properties `yaml:"catalog"`
+	ServerConfig   handlers.Config               `yaml:"server"`
+	LogConfig      logger.Config                 `yaml:"log"`
+	CORS           middleware.CORSConfig         `yaml:"cors"`
+	Admin          admin.Config                  `yaml:"admin"`
+	HTTP           server.Config                 `yaml:"http"`
+	Port           int                           `yaml:"port"`
+}
+
+func TestEnvOverrides(t *testing.T) {
+	cfg := envTestConfig{
+		DefaultCatalog: "default",
+		Catalogs: map[string]iceberg.Properties{
+			"my_cat": {"type": "sql", "warehouse": "s3://file/"},
+		},
+		Port: 8080,
+	}
+
+	require.NoError(t, envconfig.Apply("ICEBERG_REST", []string{
+		"PATH=/usr/bin",
+		"ICEBERG_REST_PORT=8181",
+		"ICEBERG_REST_DEFAULT_CATALOG=my_cat",
+		"ICEBERG_REST_LOG_DEBUG=true",
+		"ICEBERG_REST_LOG_MAX_SIZE=10",
+		"ICEBERG_REST_HTTP_SHUTDOWN_TIMEOUT=45s",
+		"ICEBERG_REST_CORS_ALLOW_ORIGINS=[https://a.example.com, https://b.example.com]",
+		"ICEBERG_REST_ADMIN={enabled: true, token: s3cret}",
+		"ICEBERG_REST_ADMIN_PORT=9191",
+		"ICEBERG_REST_SERVER_DEFAULTS_WAREHOUSE=s3://env/",
+		"ICEBERG_REST_CATALOG_MY_CAT_WAREHOUSE=s3://override/",
+		"ICEBERG_REST_CATALOG_MY_CAT_S3_SECRET__ACCESS__KEY=secret",
+		"ICEBERG_REST_CATALOG_MY_CAT={init_catalog_tables: \"true\"}",
+		"ICEBERG_REST_CATALOG_PROD_TYPE=glue",
+	}, &cfg))
+
+	assert.Equal(t, 8181, cfg.Port)
+	assert.Equal(t, "my_cat", cfg.DefaultCatalog)
+	assert.True(t, cfg.LogConfig.Debug)
+	assert.Equal(t, 10, cfg.LogConfig.MaxSize)
+	assert.Equal(t, 45*time.Second, cfg.HTTP.ShutdownTimeout)
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.CORS.AllowOrigins)
+	assert.Equal(t, admin.Config{Enabled: true, Token: "s3cret", Port: 9191}, cfg.Admin,
+		"a whole section is applied before its keys")
+	assert.Equal(t, map[string]string{"warehouse": "s3://env/"}, cfg.ServerConfig.Defaults)
+
+	assert.Equal(t, iceberg.Properties{
+		"type":                 "sql",
+		"warehouse":            "s3://override/",
+		"s3.secret-access-key": "secret",
+		"init_catalog_tables":  "true",
+	}, cfg.Catalogs["my_cat"], "names from the file are matched as a whole and properties merged")
+	assert.Equal(t, iceberg.Properties{"type": "glue"}, cfg.Catalogs["prod"])
+
+	err := envconfig.Apply("ICEBERG_REST", []string{
+		"ICEBERG_REST_PORT=eighty",
+		"ICEBERG_REST_HTTP_READ_TIMEOUT=soon",
+	}, &cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ICEBERG_REST_PORT")
+	assert.Contains(t, err.Error(), "ICEBERG_REST_HTTP_READ_TIMEOUT", "every bad variable is reported")
+	assert.Equal(t, 8181, cfg.Port)
+}
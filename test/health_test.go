@@ -0,0 +1,94 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/health"
+)
+
+type countingProbe struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (p *countingProbe) Name() string { return "counting" }
+
+func (p *countingProbe) Check(context.Context) error {
+	p.calls.Add(1)
+	return p.err
+}
+
+func TestHealthProbes(t *testing.T) {
+	backendCatalog := newSQLCatalog(t)
+
+	serve := func(checker *health.Checker) *httptest.Server {
+		gin.SetMode(gin.TestMode)
+		engine := gin.New()
+		router.SetupHealth(engine, handlers.NewHealthHandler(checker, nil))
+		return httptest.NewServer(engine)
+	}
+
+	get := func(server *httptest.Server, path string) (int, health.Report) {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var report health.Report
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		return resp.StatusCode, report
+	}
+
+	t.Run("Ready", func(t *testing.T) {
+		counter := &countingProbe{}
+		server := serve(health.NewChecker(time.Second, time.Minute,
+			health.BackendProbe(backendCatalog),
+			health.FileIOProbe("file://"+t.TempDir(), func() map[string]string { return nil }),
+			counter,
+		))
+		defer server.Close()
+
+		status, report := get(server, "/readyz")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, health.StatusUp, report.Status)
+		require.Len(t, report.Components, 3)
+		for _, component := range report.Components {
+			assert.Equal(t, health.StatusUp, component.Status, component.Name)
+			assert.NotEmpty(t, component.Latency)
+		}
+
+		// The cached report is served without probing again.
+		get(server, "/readyz")
+		assert.Equal(t, int32(1), counter.calls.Load())
+	})
+
+	t.Run("NotReady", func(t *testing.T) {
+		server := serve(health.NewChecker(time.Second, 0,
+			health.BackendProbe(backendCatalog),
+			&countingProbe{err: errors.New("connect to postgres://user:hunter22@db failed")},
+		))
+		defer server.Close()
+
+		status, report := get(server, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Equal(t, health.StatusDown, report.Status)
+		assert.Equal(t, health.StatusUp, report.Components[0].Status)
+		assert.Equal(t, health.StatusDown, report.Components[1].Status)
+		assert.NotContains(t, report.Components[1].Error, "hunter22")
+
+		status, report = get(server, "/healthz")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, health.StatusUp, report.Status)
+	})
+}
@@ -0,0 +1,166 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/server"
+)
+
+func TestServerLimits(t *testing.T) {
+	backendCatalog := newSQLCatalog(t)
+	held := newHeldCatalog(backendCatalog)
+
+	drainer := &server.Drainer{}
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(drainer.Middleware())
+	engine.Use(middleware.BodyLimit(1024))
+	router.Setup(engine, handlers.NewCatalogHandler(held, handlers.Config{}))
+
+	cfg := server.Config{
+		ReadHeaderTimeout: time.Second,
+		MaxHeaderBytes:    4096,
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := server.New(listener.Addr().String(), engine, cfg)
+	go srv.Serve(listener)
+	url := "http://" + listener.Addr().String()
+
+	restCatalog, err := rest.NewCatalog(context.Background(), "test-client", url)
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, restCatalog.CreateNamespace(ctx, table.Identifier{"limits_ns"}, nil))
+	ident := table.Identifier{"limits_ns", "tbl"}
+	_, err = restCatalog.CreateTable(ctx, ident,
+		iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64}))
+	require.NoError(t, err)
+
+	t.Run("BodySize", func(t *testing.T) {
+		post := func(body io.Reader) int {
+			resp, err := http.Post(url+"/v1/namespaces", "application/json", body)
+			require.NoError(t, err)
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		assert.Equal(t, http.StatusOK, post(strings.NewReader(`{"namespace": ["small"]}`)))
+
+		large := `{"namespace": ["large"], "properties": {"padding": "` + strings.Repeat("x", 2048) + `"}}`
+		assert.Equal(t, http.StatusRequestEntityTooLarge, post(strings.NewReader(large)))
+		// Without a Content-Length the body is sent chunked and counted as it
+		// is read.
+		assert.Equal(t, http.StatusRequestEntityTooLarge, post(io.MultiReader(strings.NewReader(large))))
+
+		exists, err := restCatalog.CheckNamespaceExists(ctx, table.Identifier{"large"})
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		rename := `{"source": {"namespace": ["limits_ns"], "name": "tbl"}, "destination": {"namespace": ["limits_ns"], "name": "` +
+			strings.Repeat("x", 2048) + `"}}`
+		resp, err := http.Post(url+"/v1/tables/rename", "application/json", io.MultiReader(strings.NewReader(rename)))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+		// Bodies are only counted as the handlers read them, not buffered up
+		// front.
+		req, err := http.NewRequest(http.MethodGet, url+"/v1/config", io.MultiReader(strings.NewReader(large)))
+		require.NoError(t, err)
+		req.Close = true
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("HeaderSize", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, url+"/v1/config", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Padding", strings.Repeat("x", 8192))
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+	})
+
+	t.Run("Drain", func(t *testing.T) {
+		commitErr := make(chan error, 1)
+		go func() {
+			_, err := restCatalog.UpdateTable(ctx, ident, nil,
+				[]table.Update{table.NewSetPropertiesUpdate(iceberg.Properties{"during": "drain"})})
+			commitErr <- err
+		}()
+		<-held.entered
+
+		shutdownErr := make(chan error, 1)
+		go func() { shutdownErr <- drainer.Shutdown(srv, 5*time.Second) }()
+		require.Eventually(t, drainer.Draining, time.Second, time.Millisecond)
+
+		// New connections are refused and requests that still get through
+		// are turned away.
+		require.Eventually(t, func() bool {
+			_, err := net.DialTimeout("tcp", listener.Addr().String(), 100*time.Millisecond)
+			return err != nil
+		}, time.Second, 10*time.Millisecond)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/config", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "close", rec.Header().Get("Connection"))
+
+		select {
+		case err := <-shutdownErr:
+			t.Fatalf("shutdown returned before the commit finished: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(held.release)
+		assert.NoError(t, <-commitErr, "the in-flight commit finishes")
+		assert.NoError(t, <-shutdownErr)
+	})
+
+	t.Run("DrainTimeout", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		block := make(chan struct{})
+		defer close(block)
+		slow := server.New(listener.Addr().String(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-block
+		}), server.Config{})
+		go slow.Serve(listener)
+
+		requestErr := make(chan error, 1)
+		go func() {
+			resp, err := http.Post("http://"+listener.Addr().String(), "text/plain", bytes.NewReader(nil))
+			if err == nil {
+				resp.Body.Close()
+			}
+			requestErr <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		start := time.Now()
+		err = (&server.Drainer{}).Shutdown(slow, 100*time.Millisecond)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Error(t, <-requestErr, "the connection is closed after the timeout")
+	})
+}
@@ -0,0 +1,54 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/loadtest"
+)
+
+// BenchmarkLoad runs the predefined operation mixes against an in-process
+// server on SQLite, with b.N calls spread over 8 clients and 4 tables. The
+// server runs the default middleware.
+// Compare two commits with benchstat:
+//
+//	go test ./test -run '^$' -bench BenchmarkLoad -benchtime 500x -count 5 > new.txt
+//	benchstat old.txt new.txt
+func BenchmarkLoad(b *testing.B) {
+	for _, name := range []string{"read-heavy", "write-heavy", "balanced"} {
+		b.Run(name, func(b *testing.B) {
+			server, err := loadtest.StartServer(b.TempDir(), loadtest.ServerConfig{})
+			require.NoError(b, err)
+			defer server.Close()
+
+			restCatalog, err := rest.NewCatalog(context.Background(), "bench-client", server.URL)
+			require.NoError(b, err)
+
+			// Only the operations are timed, not the setup here or the
+			// tables Run creates first.
+			b.StopTimer()
+			b.ResetTimer()
+			report, err := loadtest.Run(context.Background(), restCatalog, loadtest.Config{
+				Namespace:   "bench_ns",
+				Tables:      4,
+				Concurrency: 8,
+				Operations:  b.N,
+				Mix:         loadtest.Mixes[name],
+				Seed:        1,
+				Timer:       b,
+			})
+			require.NoError(b, err)
+
+			b.ReportMetric(report.Total.PerSecond, "ops/s")
+			b.ReportMetric(float64(report.Total.P50.Microseconds())/1000, "p50-ms")
+			b.ReportMetric(float64(report.Total.P99.Microseconds())/1000, "p99-ms")
+			for _, op := range report.Ops {
+				if op.Operation == loadtest.OpCommit {
+					b.ReportMetric(op.ConflictRate(), "conflicts/commit")
+				}
+			}
+		})
+	}
+}
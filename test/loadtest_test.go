@@ -0,0 +1,72 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/loadtest"
+)
+
+func TestLoadTest(t *testing.T) {
+	dir := t.TempDir()
+	server, err := loadtest.StartServer(dir, loadtest.ServerConfig{})
+	require.NoError(t, err)
+	defer server.Close()
+
+	restCatalog, err := rest.NewCatalog(context.Background(), "test-client", server.URL)
+	require.NoError(t, err)
+
+	mix, err := loadtest.ParseMix("create=1,load=4,commit=4,list=1")
+	require.NoError(t, err)
+	_, err = loadtest.ParseMix("load=1,drop=1")
+	assert.Error(t, err)
+
+	report, err := loadtest.Run(context.Background(), restCatalog, loadtest.Config{
+		Namespace:   "loadtest_ns",
+		Tables:      1,
+		Concurrency: 4,
+		Operations:  60,
+		Mix:         mix,
+		Seed:        7,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 60, report.Total.Count)
+	assert.Zero(t, report.Total.Errors)
+	sum := 0
+	for _, op := range report.Ops {
+		sum += op.Count
+		assert.LessOrEqual(t, op.P50, op.P99)
+		if op.Operation != loadtest.OpCommit {
+			assert.Zero(t, op.Conflicts, op.Operation)
+		}
+	}
+	assert.Equal(t, 60, sum)
+
+	var bench bytes.Buffer
+	require.NoError(t, report.WriteBench(&bench, "mixed"))
+	lines := strings.Split(strings.TrimSpace(bench.String()), "\n")
+	assert.Len(t, lines, len(report.Ops)+1)
+	assert.True(t, strings.HasPrefix(lines[len(lines)-1], "BenchmarkLoad/mixed/op=total\t60\t"))
+
+	// Rerunning against the same server reuses the namespace and tables.
+	_, err = loadtest.Run(context.Background(), restCatalog, loadtest.Config{
+		Namespace:  "loadtest_ns",
+		Tables:     1,
+		Operations: 5,
+		Mix:        mix,
+	})
+	require.NoError(t, err)
+
+	// Requests went through the middleware of the server.
+	log, err := os.ReadFile(filepath.Join(dir, "server.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "/v1/namespaces/loadtest_ns/tables")
+}
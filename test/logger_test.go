@@ -0,0 +1,85 @@
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+)
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	return lines
+}
+
+func TestLoggerOutputs(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "app.json")
+	consoleFile := filepath.Join(dir, "app.log")
+	accessFile := filepath.Join(dir, "access.json")
+
+	cfg := &logger.Config{
+		TimeFormat: logger.TimeFormatRFC3339,
+		Outputs: []logger.Output{
+			{Type: logger.OutputFile, FileName: jsonFile},
+			{Type: logger.OutputFile, FileName: consoleFile, Format: logger.FormatConsole},
+		},
+		AccessOutputs: []logger.Output{
+			{Type: logger.OutputFile, FileName: accessFile},
+		},
+		Sampling: logger.Sampling{Enabled: true, Burst: 2, Period: time.Hour},
+	}
+	require.NoError(t, cfg.Validate())
+	defer logger.NewLogger(&logger.Config{})
+
+	log := logger.NewLogger(cfg)
+	access := logger.NewAccessLogger(cfg)
+	require.NotNil(t, access)
+
+	for range 5 {
+		log.Info("noisy")
+	}
+	log.Warn("kept")
+	log.Warn("kept")
+	log.Warn("kept")
+	access.Info("request")
+
+	lines := readLines(t, jsonFile)
+	require.Len(t, lines, 5, "info is sampled down to the burst, warnings are not")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	_, err := time.Parse(time.RFC3339Nano, entry["time"].(string))
+	assert.NoError(t, err)
+
+	console := readLines(t, consoleFile)
+	require.Len(t, console, 5)
+	assert.True(t, strings.Contains(console[0], "INF") && strings.Contains(console[0], "noisy"), console[0])
+	assert.False(t, json.Valid([]byte(console[0])))
+
+	accessLines := readLines(t, accessFile)
+	require.Len(t, accessLines, 1)
+	assert.Contains(t, accessLines[0], `"message":"request"`)
+
+	assert.Nil(t, logger.NewAccessLogger(&logger.Config{}))
+	assert.Error(t, (&logger.Config{Outputs: []logger.Output{{Type: "syslog"}}}).Validate())
+	assert.Error(t, (&logger.Config{Outputs: []logger.Output{{Type: logger.OutputFile}}}).Validate())
+	assert.Error(t, (&logger.Config{Format: "xml"}).Validate())
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/metrics"
+)
+
+// scrapeMetrics returns the registry in the Prometheus exposition format.
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	backendCatalog := newSQLCatalog(t)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.Metrics())
+	router.SetupMetrics(engine, "/metrics")
+	router.Setup(engine, handlers.NewCatalogHandler(metrics.InstrumentCatalog(backendCatalog), handlers.Config{}))
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	restCatalog, err := rest.NewCatalog(context.Background(), "test-client", server.URL)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, restCatalog.CreateNamespace(ctx, table.Identifier{"metrics_ns"}, nil))
+
+	tableIdent := table.Identifier{"metrics_ns", "tbl"}
+	_, err = restCatalog.CreateTable(ctx, tableIdent,
+		iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64}))
+	require.NoError(t, err)
+
+	_, err = restCatalog.LoadTable(ctx, tableIdent, nil)
+	require.NoError(t, err)
+
+	// A stale schema requirement loses against the current metadata.
+	_, err = restCatalog.UpdateTable(ctx, tableIdent,
+		[]table.Requirement{table.AssertCurrentSchemaID(42)},
+		[]table.Update{table.NewSetPropertiesUpdate(iceberg.Properties{"a": "b"})})
+	assert.ErrorIs(t, err, rest.ErrCommitFailed)
+
+	require.NoError(t, metrics.RefreshCatalogStats(ctx, backendCatalog))
+
+	resp, err := http.Get(server.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	text := string(body)
+	assert.Contains(t, text, `iceberg_rest_http_requests_total{method="GET",route="/v1/namespaces/:namespace/tables/:table",status="200"}`)
+	assert.Contains(t, text, `iceberg_rest_commits_total{result="conflict"}`)
+	assert.Contains(t, text, `iceberg_rest_catalog_operation_duration_seconds_count{operation="LoadTable",result="ok"}`)
+	assert.Contains(t, text, "iceberg_rest_catalog_tables 1")
+	assert.Contains(t, text, "iceberg_rest_catalog_namespaces 1")
+	assert.NotContains(t, text, "metrics_ns")
+}
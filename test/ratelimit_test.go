@@ -0,0 +1,94 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.Principal("X-Principal"))
+	engine.Use(middleware.RateLimit(middleware.RateLimitConfig{
+		Rules: []middleware.RateLimitRule{{
+			Key:    middleware.RateLimitByPrincipal,
+			Routes: []string{"GET /v1/config"},
+			Rate:   0.001,
+			Burst:  2,
+		}},
+	}))
+	router.Setup(engine, handlers.NewCatalogHandler(nil, handlers.Config{}))
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	get := func(path, principal string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Principal", principal)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	assert.Equal(t, http.StatusOK, get("/v1/config", "alice").StatusCode)
+	assert.Equal(t, http.StatusOK, get("/v1/config", "alice").StatusCode)
+
+	resp := get("/v1/config", "alice")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+
+	// Other principals and routes outside the group have their own budget.
+	assert.Equal(t, http.StatusOK, get("/v1/config", "bob").StatusCode)
+	assert.Equal(t, http.StatusOK, get("/health", "alice").StatusCode)
+}
+
+func TestRateLimitRules(t *testing.T) {
+	t.Run("RejectedRequestsKeepTheirTokens", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		engine := gin.New()
+		engine.Use(middleware.RateLimit(middleware.RateLimitConfig{
+			Rules: []middleware.RateLimitRule{
+				{Key: middleware.RateLimitByClientIP, Rate: 0.001, Burst: 2},
+				{Key: middleware.RateLimitByClientIP, Routes: []string{"GET /v1/config"}, Rate: 0.001, Burst: 1},
+			},
+		}))
+		router.Setup(engine, handlers.NewCatalogHandler(nil, handlers.Config{}))
+
+		get := func(path string) int {
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusOK, get("/v1/config"))
+		assert.Equal(t, http.StatusTooManyRequests, get("/v1/config"), "the route rule is exhausted")
+		assert.Equal(t, http.StatusOK, get("/health"), "the rejected request gave its global token back")
+		assert.Equal(t, http.StatusTooManyRequests, get("/health"))
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		require.NoError(t, middleware.RateLimitConfig{Rules: []middleware.RateLimitRule{
+			{Key: middleware.RateLimitByPrincipal, Rate: 10, Burst: 20},
+		}}.Validate())
+
+		got := problems(t, middleware.RateLimitConfig{Rules: []middleware.RateLimitRule{
+			{Key: "user", Rate: 10, Burst: 20},
+			{Key: middleware.RateLimitByClientIP, Rate: 0, Burst: 0},
+		}}.Validate())
+		assert.Equal(t, map[string]string{
+			"rules[0].key":   `unknown rate limit key "user", expected principal or client-ip`,
+			"rules[1].rate":  "must be positive",
+			"rules[1].burst": "must be at least 1",
+		}, got)
+	})
+}
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/admin"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+)
+
+func TestReadOnlyMode(t *testing.T) {
+	backendCatalog := newSQLCatalog(t)
+
+	readOnly := middleware.NewReadOnly(middleware.ReadOnlyConfig{})
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(readOnly.Middleware("test"))
+	router.SetupReadOnlySwitch(engine, admin.NewHandler(readOnly), middleware.BearerAuth("operator-token"))
+	router.Setup(engine, handlers.NewCatalogHandler(backendCatalog, handlers.Config{}))
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	restCatalog, err := rest.NewCatalog(context.Background(), "test-client", server.URL)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, restCatalog.CreateNamespace(ctx, table.Identifier{"frozen"}, nil))
+	require.NoError(t, restCatalog.CreateNamespace(ctx, table.Identifier{"open"}, nil))
+
+	putReadOnly := func(cfg middleware.ReadOnlyConfig, token string) int {
+		body, err := json.Marshal(cfg)
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPut, server.URL+"/admin/read-only", bytes.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	setReadOnly := func(cfg middleware.ReadOnlyConfig) {
+		require.Equal(t, http.StatusOK, putReadOnly(cfg, "operator-token"))
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, putReadOnly(middleware.ReadOnlyConfig{Enabled: true}, ""))
+		assert.Equal(t, http.StatusUnauthorized, putReadOnly(middleware.ReadOnlyConfig{Enabled: true}, "guess"))
+		assert.False(t, readOnly.Get().Enabled)
+
+		resp, err := http.Get(server.URL + "/admin/read-only")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Namespace", func(t *testing.T) {
+		setReadOnly(middleware.ReadOnlyConfig{Namespaces: []string{"frozen"}})
+
+		err := restCatalog.CreateNamespace(ctx, table.Identifier{"frozen", "child"}, nil)
+		assert.ErrorIs(t, err, rest.ErrServiceUnavailable)
+
+		_, err = restCatalog.UpdateNamespaceProperties(ctx, table.Identifier{"frozen"}, nil, iceberg.Properties{"a": "b"})
+		assert.ErrorIs(t, err, rest.ErrServiceUnavailable)
+
+		_, err = restCatalog.UpdateNamespaceProperties(ctx, table.Identifier{"open"}, nil, iceberg.Properties{"a": "b"})
+		assert.NoError(t, err)
+
+		_, err = restCatalog.LoadNamespaceProperties(ctx, table.Identifier{"frozen"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Catalog", func(t *testing.T) {
+		setReadOnly(middleware.ReadOnlyConfig{Catalogs: []string{"test"}, RetryAfter: 30})
+
+		resp, err := http.Post(server.URL+"/v1/namespaces", "application/json",
+			bytes.NewReader([]byte(`{"namespace": ["other"]}`)))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+
+		namespaces, err := restCatalog.ListNamespaces(ctx, nil)
+		require.NoError(t, err)
+		assert.Len(t, namespaces, 2)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		setReadOnly(middleware.ReadOnlyConfig{})
+
+		require.NoError(t, restCatalog.DropNamespace(ctx, table.Identifier{"frozen"}))
+	})
+}
@@ -0,0 +1,153 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/cache"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+	"github.com/xixipi-lining/iceberg-rest-catalog/redact"
+)
+
+func TestRedaction(t *testing.T) {
+	redactor, err := redact.New(redact.Config{Keys: []string{"internal.api-key"}})
+	require.NoError(t, err)
+
+	config := handlers.Config{
+		Defaults: map[string]string{
+			"warehouse":            "/tmp/warehouse",
+			"s3.secret-access-key": "password123",
+			"internal.api-key":     "abcdef",
+			"backup.uri":           "postgres://user:hunter22@db:5432/catalog",
+		},
+	}
+	redactor.AddSecrets(config.Defaults)
+
+	backendCatalog := newSQLCatalog(t)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	router.Setup(engine, handlers.NewCatalogHandler(backendCatalog, config, handlers.WithRedactor(redactor)))
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	t.Run("GetConfig", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/v1/config")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var got handlers.Config
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		assert.Equal(t, "/tmp/warehouse", got.Defaults["warehouse"])
+		assert.Equal(t, redact.Mask, got.Defaults["s3.secret-access-key"])
+		assert.Equal(t, redact.Mask, got.Defaults["internal.api-key"])
+		assert.Equal(t, "postgres://user:****@db:5432/catalog", got.Defaults["backup.uri"])
+	})
+
+	t.Run("TableMetadata", func(t *testing.T) {
+		restCatalog, err := rest.NewCatalog(context.Background(), "test-client", server.URL)
+		require.NoError(t, err)
+
+		metadataProperties := func(t *testing.T, raw json.RawMessage) map[string]string {
+			t.Helper()
+			var md struct {
+				Properties map[string]string `json:"properties"`
+			}
+			require.NoError(t, json.Unmarshal(raw, &md))
+			return md.Properties
+		}
+
+		ctx := context.Background()
+		require.NoError(t, restCatalog.CreateNamespace(ctx, table.Identifier{"redact_ns"}, nil))
+		created, err := restCatalog.CreateTable(ctx, table.Identifier{"redact_ns", "tbl"},
+			iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64}),
+			catalog.WithProperties(iceberg.Properties{"s3.session-token": "tok-1234", "owner": "alice"}))
+		require.NoError(t, err)
+		assert.Equal(t, redact.Mask, created.Properties()["s3.session-token"], "the create response is masked")
+		assert.Equal(t, "alice", created.Properties()["owner"])
+
+		resp, err := http.Get(server.URL + "/v1/namespaces/redact_ns/tables/tbl")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var got handlers.LoadTableResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		assert.Equal(t, redact.Mask, got.Config["s3.session-token"])
+		assert.Equal(t, "alice", got.Config["owner"])
+		props := metadataProperties(t, got.Metadata)
+		assert.Equal(t, redact.Mask, props["s3.session-token"])
+		assert.Equal(t, "alice", props["owner"])
+		assert.NotContains(t, string(got.Metadata), "tok-1234")
+
+		body, err := json.Marshal(map[string]any{
+			"requirements": []any{},
+			"updates": []any{map[string]any{
+				"action":  "set-properties",
+				"updates": map[string]string{"s3.secret-access-key": "updated-secret"},
+			}},
+		})
+		require.NoError(t, err)
+		resp, err = http.Post(server.URL+"/v1/namespaces/redact_ns/tables/tbl", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var updated handlers.UpdateTableResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&updated))
+		props = metadataProperties(t, updated.Metadata)
+		assert.Equal(t, redact.Mask, props["s3.secret-access-key"], "the commit response is masked")
+		assert.Equal(t, redact.Mask, props["s3.session-token"])
+		assert.NotContains(t, string(updated.Metadata), "updated-secret")
+
+		assert.Equal(t, "token tok-1234", redactor.String("token tok-1234"), "served table properties don't grow the set of secrets")
+	})
+
+	t.Run("CachedMetadata", func(t *testing.T) {
+		cached := cache.New(backendCatalog, cache.Config{Enabled: true, TTL: time.Minute})
+		engine := gin.New()
+		router.Setup(engine, handlers.NewCatalogHandler(cached, config,
+			handlers.WithRedactor(redactor),
+			handlers.WithMetadataEncoder(cached),
+		))
+		cachedServer := httptest.NewServer(engine)
+		defer cachedServer.Close()
+
+		for range 2 {
+			resp, err := http.Get(cachedServer.URL + "/v1/namespaces/redact_ns/tables/tbl")
+			require.NoError(t, err)
+			data, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			require.NoError(t, err)
+			assert.NotContains(t, string(data), "tok-1234")
+			assert.NotContains(t, string(data), "updated-secret")
+		}
+	})
+
+	t.Run("Logs", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := logger.NewLogger(&logger.Config{}, logger.WithOutputFilter(func(w io.Writer) io.Writer {
+			return redactor.Writer(&buf)
+		}))
+		log.Errorf("failed to connect with secret %s to %s", "password123", "postgres://user:hunter22@db/catalog")
+
+		assert.NotContains(t, buf.String(), "password123")
+		assert.NotContains(t, buf.String(), "hunter22")
+		assert.Contains(t, buf.String(), redact.Mask)
+	})
+}
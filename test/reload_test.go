@@ -0,0 +1,243 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/cache"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+	"github.com/xixipi-lining/iceberg-rest-catalog/reload"
+)
+
+// closingCatalog records whether it was closed. Its CheckNamespaceExists
+// blocks until release is closed, if set.
+type closingCatalog struct {
+	catalog.Catalog
+	release chan struct{}
+	calls   atomic.Int32
+	closed  atomic.Bool
+}
+
+func (c *closingCatalog) CheckNamespaceExists(context.Context, table.Identifier) (bool, error) {
+	c.calls.Add(1)
+	if c.release != nil {
+		<-c.release
+	}
+	return true, nil
+}
+
+func (c *closingCatalog) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+func TestConfigReload(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("SwapCatalog", func(t *testing.T) {
+		first := newSQLCatalog(t)
+		second := newSQLCatalog(t)
+
+		backend := reload.NewCatalog(first)
+		cached := cache.New(backend, cache.Config{Enabled: true, TTL: time.Minute})
+
+		gin.SetMode(gin.TestMode)
+		engine := gin.New()
+		router.Setup(engine, handlers.NewCatalogHandler(cached, handlers.Config{},
+			handlers.WithMetadataEncoder(cached),
+		))
+		server := httptest.NewServer(engine)
+		defer server.Close()
+
+		restCatalog, err := rest.NewCatalog(ctx, "test-client", server.URL)
+		require.NoError(t, err)
+
+		schema := iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64})
+		ident := table.Identifier{"reload_ns", "events"}
+		require.NoError(t, restCatalog.CreateNamespace(ctx, ident[:1], nil))
+		_, err = restCatalog.CreateTable(ctx, ident, schema)
+		require.NoError(t, err)
+		_, err = restCatalog.LoadTable(ctx, ident, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, <-backend.Swap(second), "the replaced catalog is closed")
+		cached.Purge()
+
+		exists, err := restCatalog.CheckNamespaceExists(ctx, ident[:1])
+		require.NoError(t, err)
+		assert.False(t, exists, "calls go to the swapped in catalog")
+		_, err = restCatalog.LoadTable(ctx, ident, nil)
+		assert.ErrorIs(t, err, catalog.ErrNoSuchTable, "the cache no longer serves tables of the old catalog")
+
+		require.NoError(t, restCatalog.CreateNamespace(ctx, table.Identifier{"other_ns"}, nil))
+		exists, err = second.CheckNamespaceExists(ctx, table.Identifier{"other_ns"})
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("CloseAfterCalls", func(t *testing.T) {
+		first := &closingCatalog{release: make(chan struct{})}
+		backend := reload.NewCatalog(first)
+
+		started := make(chan struct{})
+		go func() {
+			close(started)
+			backend.CheckNamespaceExists(ctx, table.Identifier{"ns"})
+		}()
+		<-started
+		require.Eventually(t, func() bool { return first.calls.Load() == 1 }, 5*time.Second, time.Millisecond)
+
+		second := &closingCatalog{}
+		closed := backend.Swap(second)
+		exists, err := backend.CheckNamespaceExists(ctx, table.Identifier{"ns"})
+		require.NoError(t, err)
+		assert.True(t, exists, "calls after the swap go to the new catalog")
+		assert.Equal(t, int32(1), second.calls.Load())
+
+		select {
+		case <-closed:
+			t.Fatal("closed while a call was running on it")
+		case <-time.After(50 * time.Millisecond):
+		}
+		assert.False(t, first.closed.Load())
+
+		close(first.release)
+		select {
+		case err := <-closed:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("replaced catalog was not closed")
+		}
+		assert.True(t, first.closed.Load())
+		assert.False(t, second.closed.Load())
+
+		// A catalog without Close is dropped once replaced.
+		backend.Swap(struct{ catalog.Catalog }{})
+		assert.NoError(t, <-backend.Swap(second))
+	})
+
+	t.Run("HandlerConfig", func(t *testing.T) {
+		handler := handlers.NewCatalogHandler(newSQLCatalog(t), handlers.Config{
+			Defaults: map[string]string{"warehouse": "s3://old/"},
+		})
+
+		gin.SetMode(gin.TestMode)
+		engine := gin.New()
+		router.Setup(engine, handler)
+		server := httptest.NewServer(engine)
+		defer server.Close()
+
+		getConfig := func() handlers.Config {
+			resp, err := http.Get(server.URL + "/v1/config")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			var cfg handlers.Config
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&cfg))
+			return cfg
+		}
+
+		assert.Equal(t, "s3://old/", getConfig().Defaults["warehouse"])
+
+		handler.SetConfig(handlers.Config{
+			Defaults:  map[string]string{"warehouse": "s3://new/"},
+			Overrides: map[string]string{"s3.secret-access-key": "reloaded-secret"},
+		})
+		cfg := getConfig()
+		assert.Equal(t, "s3://new/", cfg.Defaults["warehouse"])
+		assert.NotEqual(t, "reloaded-secret", cfg.Overrides["s3.secret-access-key"], "reloaded secrets are still masked")
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("port: 8080\n"), 0o600))
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		trigger := make(chan os.Signal, 1)
+		reloads := make(chan struct{}, 10)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			reload.Watch(ctx, path, reload.Config{Watch: true, Interval: 10 * time.Millisecond}, trigger, func() {
+				reloads <- struct{}{}
+			})
+		}()
+
+		select {
+		case <-reloads:
+			t.Fatal("reloaded an unchanged file")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		require.NoError(t, os.WriteFile(path, []byte("port: 8081\n"), 0o600))
+		select {
+		case <-reloads:
+		case <-time.After(5 * time.Second):
+			t.Fatal("change was not picked up")
+		}
+
+		// A file being replaced is skipped rather than reloaded.
+		require.NoError(t, os.Remove(path))
+		select {
+		case <-reloads:
+			t.Fatal("reloaded a missing file")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		trigger <- os.Interrupt
+		select {
+		case <-reloads:
+		case <-time.After(5 * time.Second):
+			t.Fatal("trigger did not reload")
+		}
+
+		cancel()
+		<-done
+	})
+
+	t.Run("Logger", func(t *testing.T) {
+		dir := t.TempDir()
+		before := filepath.Join(dir, "before.json")
+		after := filepath.Join(dir, "after.json")
+		defer logger.NewLogger(&logger.Config{})
+
+		log := logger.NewLogger(&logger.Config{
+			Outputs: []logger.Output{{Type: logger.OutputFile, FileName: before}},
+		})
+		named := log.Named("http")
+
+		named.Debug("hidden")
+		named.Info("first")
+
+		logger.Reload(log, &logger.Config{
+			Debug:   true,
+			Outputs: []logger.Output{{Type: logger.OutputFile, FileName: after}},
+		})
+		named.Debug("second")
+
+		lines := readLines(t, before)
+		require.Len(t, lines, 1)
+		assert.Contains(t, lines[0], `"message":"first"`)
+
+		lines = readLines(t, after)
+		require.Len(t, lines, 1, "loggers handed out before the reload follow it")
+		assert.Contains(t, lines[0], `"message":"second"`)
+	})
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/apierror"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+)
+
+func TestRequestID(t *testing.T) {
+	backendCatalog := newSQLCatalog(t)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.Logger(logger.NewLogger(&logger.Config{})))
+	router.Setup(engine, handlers.NewCatalogHandler(backendCatalog, handlers.Config{}))
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	get := func(headers map[string]string) (string, apierror.Response) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/v1/namespaces/missing", nil)
+		require.NoError(t, err)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		var body apierror.Response
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.Header.Get(middleware.RequestIDHeader), body
+	}
+
+	t.Run("Incoming", func(t *testing.T) {
+		header, body := get(map[string]string{"X-Request-ID": "client-42"})
+		assert.Equal(t, "client-42", header)
+		assert.Equal(t, "client-42", body.Error.RequestID)
+	})
+
+	t.Run("Traceparent", func(t *testing.T) {
+		header, body := get(map[string]string{
+			"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		})
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", header)
+		assert.Equal(t, header, body.Error.RequestID)
+	})
+
+	t.Run("Generated", func(t *testing.T) {
+		header, body := get(map[string]string{"X-Request-ID": "bad id"})
+		assert.NotEmpty(t, header)
+		assert.NotEqual(t, "bad id", header)
+		assert.Equal(t, header, body.Error.RequestID)
+	})
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/iceberg-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/redact"
+	"github.com/xixipi-lining/iceberg-rest-catalog/secret"
+	"github.com/xixipi-lining/iceberg-rest-catalog/validate"
+)
+
+func TestSecretReferences(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("EnvAndFile", func(t *testing.T) {
+		t.Setenv("TEST_S3_ACCESS_KEY", "AKIAEXAMPLE")
+		path := filepath.Join(t.TempDir(), "secret-access-key")
+		require.NoError(t, os.WriteFile(path, []byte("wJalrXUtnFEMI\n"), 0o600))
+
+		props := map[string]string{
+			"type":                 "sql",
+			"s3.access-key-id":     "${env:TEST_S3_ACCESS_KEY}",
+			"s3.secret-access-key": "${file:" + path + "}",
+			"uri":                  "postgres://app:${env:TEST_S3_ACCESS_KEY}@db/catalog",
+			"warehouse":            "s3://$${bucket}/",
+		}
+		resolved, values, err := secret.NewResolver().Properties(ctx, props)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"type":                 "sql",
+			"s3.access-key-id":     "AKIAEXAMPLE",
+			"s3.secret-access-key": "wJalrXUtnFEMI",
+			"uri":                  "postgres://app:AKIAEXAMPLE@db/catalog",
+			"warehouse":            "s3://${bucket}/",
+		}, resolved)
+		assert.ElementsMatch(t, []string{"AKIAEXAMPLE", "AKIAEXAMPLE", "wJalrXUtnFEMI"}, values)
+		assert.Equal(t, "${env:TEST_S3_ACCESS_KEY}", props["s3.access-key-id"], "the references are kept")
+	})
+
+	t.Run("Provider", func(t *testing.T) {
+		vault := map[string]string{"kv/catalog#password": "hunter22"}
+		resolver := secret.NewResolver()
+		resolver.Register("vault", secret.ProviderFunc(func(_ context.Context, ref string) (string, error) {
+			value, ok := vault[ref]
+			if !ok {
+				return "", errors.New("no such secret")
+			}
+			return value, nil
+		}))
+
+		value, err := resolver.Resolve(ctx, "${vault:kv/catalog#password}")
+		require.NoError(t, err)
+		assert.Equal(t, "hunter22", value)
+
+		_, err = resolver.Resolve(ctx, "${vault:kv/missing}")
+		assert.ErrorContains(t, err, "${vault:kv/missing}: no such secret")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Setenv("TEST_SECRET", "do-not-print")
+		resolver := secret.NewResolver()
+
+		_, _, err := resolver.Properties(ctx, map[string]string{
+			"token":                "${env:TEST_UNSET_SECRET}",
+			"s3.secret-access-key": "${file:" + filepath.Join(t.TempDir(), "missing") + "}",
+			"credential":           "prefix-${env:TEST_SECRET}-${vault:x}",
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "token: resolving ${env:TEST_UNSET_SECRET}: environment variable TEST_UNSET_SECRET is not set")
+		assert.Contains(t, err.Error(), "s3.secret-access-key: resolving ${file:")
+		assert.Contains(t, err.Error(), `credential: unknown secret provider "vault"`, "every property is reported")
+		assert.NotContains(t, err.Error(), "do-not-print")
+
+		assert.NoError(t, resolver.Check("${env:A} and $${literal}"))
+		assert.ErrorContains(t, resolver.Check("${env:A"), "unterminated reference")
+		assert.ErrorContains(t, resolver.Check("${A}"), "malformed reference")
+		assert.ErrorContains(t, resolver.Check("${aws:A}"), `unknown secret provider "aws"`)
+	})
+
+	t.Run("Redaction", func(t *testing.T) {
+		t.Setenv("TEST_DB_PASSWORD", "pg-pass-123")
+		resolver := secret.NewResolver()
+		_, values, err := resolver.Properties(ctx, map[string]string{"uri": "postgres://app:${env:TEST_DB_PASSWORD}@db/catalog"})
+		require.NoError(t, err)
+
+		redactor := redact.Default()
+		redactor.AddValues(values...)
+		assert.Equal(t, "connecting as ****: refused", redactor.String("connecting as pg-pass-123: refused"),
+			"resolved values are scrubbed whatever key they came from")
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		var r validate.Report
+		validate.Catalog(&r, "catalog.default", iceberg.Properties{
+			"type":        "sql",
+			"sql.driver":  "${env:SQL_DRIVER}",
+			"sql.dialect": "${env:SQL_DIALECT}",
+		})
+		assert.NoError(t, r.Err(), "references are only checked once resolved")
+	})
+}
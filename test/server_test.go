@@ -17,19 +17,21 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
 	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/loadtest"
 )
 
+// newSQLCatalog returns a SQLite catalog backing a test server, with its
+// database and warehouse in a temporary directory of t.
+func newSQLCatalog(t testing.TB) catalog.Catalog {
+	t.Helper()
+	cat, err := loadtest.OpenCatalog(t.TempDir())
+	require.NoError(t, err)
+	return cat
+}
+
 // setupTestServer creates a test HTTP server
 func setupTestServer(t *testing.T) (*httptest.Server, *rest.Catalog) {
-	// Create in-memory SQLite catalog as backend
-	backendCatalog, err := catalog.Load(context.Background(), "test", iceberg.Properties{
-		"type":                "sql",
-		"sql.driver":          "sqlite3",
-		"sql.dialect":         "sqlite",
-		"init_catalog_tables": "true",
-		"warehouse":           "/tmp/warehouse",
-	})
-	require.NoError(t, err)
+	backendCatalog := newSQLCatalog(t)
 
 	// Create handler
 	config := handlers.Config{
@@ -284,6 +286,17 @@ func TestErrorHandling(t *testing.T) {
 		assert.ErrorIs(t, err, catalog.ErrNoSuchTable)
 	})
 
+	t.Run("UpdateNonExistentTable", func(t *testing.T) {
+		ident := table.Identifier{"test_namespace", "never_created"}
+		_, err := restCatalog.UpdateTable(ctx, ident, nil,
+			[]table.Update{table.NewSetPropertiesUpdate(iceberg.Properties{"owner": "nobody"})})
+		assert.ErrorIs(t, err, catalog.ErrNoSuchTable)
+
+		exists, err := restCatalog.CheckTableExists(ctx, ident)
+		require.NoError(t, err)
+		assert.False(t, exists, "the commit doesn't create the table")
+	})
+
 	t.Run("DuplicateNamespace", func(t *testing.T) {
 		// Create namespace
 		namespace := table.Identifier{"duplicate_test"}
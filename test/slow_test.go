@@ -0,0 +1,81 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/phases"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+)
+
+func TestSlowRequests(t *testing.T) {
+	backendCatalog := newSQLCatalog(t)
+
+	logFile := filepath.Join(t.TempDir(), "slow.log")
+	log := logger.NewLogger(&logger.Config{FileName: logFile})
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.Principal("X-Principal"))
+	// Only table responses are larger than 512 bytes.
+	engine.Use(middleware.SlowRequests(middleware.SlowRequestConfig{ResponseSize: 512}, log))
+	router.SetupMetrics(engine, "/metrics")
+	router.Setup(engine, handlers.NewCatalogHandler(backendCatalog, handlers.Config{}))
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	restCatalog, err := rest.NewCatalog(context.Background(), "test-client", server.URL)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, restCatalog.CreateNamespace(ctx, table.Identifier{"slow_ns"}, nil))
+	tableIdent := table.Identifier{"slow_ns", "wide"}
+	_, err = restCatalog.CreateTable(ctx, tableIdent,
+		iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64}))
+	require.NoError(t, err)
+	_, err = restCatalog.LoadTable(ctx, tableIdent, nil)
+	require.NoError(t, err)
+
+	var loads []map[string]any
+	for _, line := range readLines(t, logFile) {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		assert.Equal(t, "slow request", entry["message"])
+		if entry["method"] == http.MethodGet {
+			loads = append(loads, entry)
+		}
+	}
+	require.Len(t, loads, 1, "only the table load and create are flagged")
+
+	entry := loads[0]
+	assert.Equal(t, "/v1/namespaces/:namespace/tables/:table", entry["route"])
+	assert.Equal(t, "slow_ns", entry["namespace"])
+	assert.Equal(t, "wide", entry["table"])
+	assert.Equal(t, "anonymous", entry["principal"])
+	assert.Equal(t, []any{middleware.SlowReasonSize}, entry["reasons"])
+	for _, phase := range []string{phases.Backend, phases.Marshal, phases.Write} {
+		assert.Contains(t, entry, "phase."+phase)
+	}
+
+	resp, err := http.Get(server.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `iceberg_rest_http_slow_requests_total{reason="size",route="/v1/namespaces/:namespace/tables/:table"}`)
+}
@@ -0,0 +1,171 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/catalog/rest"
+	"github.com/apache/iceberg-go/table"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+)
+
+// failingListCatalog fails ListTables after yielding failAfter identifiers.
+type failingListCatalog struct {
+	catalog.Catalog
+	failAfter int
+}
+
+func (c *failingListCatalog) ListTables(ctx context.Context, namespace table.Identifier) iter.Seq2[table.Identifier, error] {
+	return func(yield func(table.Identifier, error) bool) {
+		n := 0
+		for ident, err := range c.Catalog.ListTables(ctx, namespace) {
+			if n == c.failAfter {
+				break
+			}
+			if !yield(ident, err) {
+				return
+			}
+			n++
+		}
+		yield(nil, errors.New("backend went away"))
+	}
+}
+
+func TestStreamingResponses(t *testing.T) {
+	backendCatalog := newSQLCatalog(t)
+
+	gin.SetMode(gin.TestMode)
+	newServer := func(cat catalog.Catalog) *httptest.Server {
+		engine := gin.New()
+		router.Setup(engine, handlers.NewCatalogHandler(cat, handlers.Config{}))
+		server := httptest.NewServer(engine)
+		t.Cleanup(server.Close)
+		return server
+	}
+	server := newServer(backendCatalog)
+
+	restCatalog, err := rest.NewCatalog(context.Background(), "test-client", server.URL)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	ns := table.Identifier{"stream_ns"}
+	require.NoError(t, restCatalog.CreateNamespace(ctx, ns, nil))
+	require.NoError(t, restCatalog.CreateNamespace(ctx, table.Identifier{"stream_empty"}, nil))
+	schema := iceberg.NewSchema(0, iceberg.NestedField{ID: 1, Name: "id", Type: iceberg.PrimitiveTypes.Int64})
+	const numTables = 300
+	for i := range numTables {
+		_, err := restCatalog.CreateTable(ctx, table.Identifier{"stream_ns", fmt.Sprintf("tbl_%03d", i)}, schema,
+			catalog.WithProperties(iceberg.Properties{"owner": "streaming"}))
+		require.NoError(t, err)
+	}
+
+	get := func(t *testing.T, url string) (*http.Response, []byte) {
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, body
+	}
+
+	t.Run("ListTables", func(t *testing.T) {
+		var names []string
+		for ident, err := range restCatalog.ListTables(ctx, ns) {
+			require.NoError(t, err)
+			names = append(names, ident[len(ident)-1])
+		}
+		assert.Len(t, names, numTables)
+		assert.Contains(t, names, "tbl_042")
+
+		resp, body := get(t, server.URL+"/v1/namespaces/stream_ns/tables")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+		var list handlers.ListTablesResponse
+		require.NoError(t, json.Unmarshal(body, &list))
+		require.Len(t, list.Identifiers, numTables)
+		assert.Equal(t, handlers.Identifier{Namespace: []string{"stream_ns"}, Name: "tbl_000"}, list.Identifiers[0])
+
+		resp, body = get(t, server.URL+"/v1/namespaces/stream_empty/tables")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.JSONEq(t, `{"identifiers": null}`, string(body))
+	})
+
+	t.Run("ListTablesBackendError", func(t *testing.T) {
+		failing := newServer(&failingListCatalog{Catalog: backendCatalog, failAfter: 0})
+		resp, body := get(t, failing.URL+"/v1/namespaces/stream_ns/tables")
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Contains(t, string(body), "InternalServerError")
+
+		// Once the list is under way the status is sent, the truncated body
+		// must not parse.
+		failing = newServer(&failingListCatalog{Catalog: backendCatalog, failAfter: 10})
+		resp, body = get(t, failing.URL+"/v1/namespaces/stream_ns/tables")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var list handlers.ListTablesResponse
+		assert.Error(t, json.Unmarshal(body, &list))
+	})
+
+	// The encoding of both metadata versions is that of json.Marshal, with the
+	// lists that grow with the table's history encoded an element at a time.
+	for _, version := range []string{"1", "2"} {
+		t.Run("LoadTableV"+version, func(t *testing.T) {
+			ident := table.Identifier{"stream_ns", "history_v" + version}
+			_, err := restCatalog.CreateTable(ctx, ident, schema,
+				catalog.WithProperties(iceberg.Properties{"owner": "streaming", "format-version": version}))
+			require.NoError(t, err)
+
+			for i := range int64(3) {
+				snapshot := &table.Snapshot{
+					SnapshotID:     i + 1,
+					SequenceNumber: i + 1,
+					TimestampMs:    1700000000000 + i,
+					ManifestList:   fmt.Sprintf("file:///tmp/warehouse/snap-%d.avro", i+1),
+					Summary:        &table.Summary{Operation: table.OpAppend},
+				}
+				if version == "1" {
+					snapshot.SequenceNumber = 0
+				}
+				if i > 0 {
+					snapshot.ParentSnapshotID = &i
+				}
+				_, err := restCatalog.UpdateTable(ctx, ident, nil, []table.Update{
+					table.NewAddSnapshotUpdate(snapshot),
+					table.NewSetSnapshotRefUpdate(table.MainBranch, snapshot.SnapshotID, table.BranchRef, -1, -1, -1),
+				})
+				require.NoError(t, err)
+			}
+
+			tbl, err := backendCatalog.LoadTable(ctx, ident, nil)
+			require.NoError(t, err)
+			assert.Equal(t, version, fmt.Sprint(tbl.Metadata().Version()))
+			assert.Equal(t, "streaming", tbl.Properties()["owner"])
+			require.Len(t, slices.Collect(tbl.Metadata().PreviousFiles()), 3)
+			require.Len(t, slices.Collect(tbl.Metadata().SnapshotLogs()), 3)
+
+			resp, body := get(t, server.URL+"/v1/namespaces/stream_ns/tables/history_v"+version)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			var loaded handlers.LoadTableResponse
+			require.NoError(t, json.Unmarshal(body, &loaded))
+			assert.Equal(t, tbl.MetadataLocation(), loaded.MetadataLoc)
+			assert.Equal(t, "streaming", loaded.Config["owner"])
+
+			expected, err := json.Marshal(tbl.Metadata())
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(loaded.Metadata), "the streamed encoding is that of json.Marshal")
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/handlers"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/router"
+	"github.com/xixipi-lining/iceberg-rest-catalog/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	backendCatalog := newSQLCatalog(t)
+	require.NoError(t, backendCatalog.CreateNamespace(context.Background(), []string{"traced"}, nil))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(otelgin.Middleware("test"))
+	router.Setup(engine, handlers.NewCatalogHandler(tracing.InstrumentCatalog(backendCatalog), handlers.Config{}))
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	const (
+		traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentSpanID = "00f067aa0ba902b7"
+	)
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v1/namespaces/traced", nil)
+	require.NoError(t, err)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	backendSpan, serverSpan := spans[0], spans[1]
+	assert.Equal(t, "GET /v1/namespaces/:namespace", serverSpan.Name)
+	assert.Equal(t, traceID, serverSpan.SpanContext.TraceID().String())
+	assert.Equal(t, parentSpanID, serverSpan.Parent.SpanID().String())
+
+	assert.Equal(t, "catalog.LoadNamespaceProperties", backendSpan.Name)
+	assert.Equal(t, traceID, backendSpan.SpanContext.TraceID().String())
+	assert.Equal(t, serverSpan.SpanContext.SpanID(), backendSpan.Parent.SpanID())
+}
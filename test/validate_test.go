@@ -0,0 +1,133 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/iceberg-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xixipi-lining/iceberg-rest-catalog/api/middleware"
+	"github.com/xixipi-lining/iceberg-rest-catalog/logger"
+	"github.com/xixipi-lining/iceberg-rest-catalog/validate"
+)
+
+func problems(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var errs validate.Errors
+	require.True(t, errors.As(err, &errs), "expected validate.Errors, got %v", err)
+	byPath := make(map[string]string, len(errs))
+	for _, p := range errs {
+		byPath[p.Path] = p.Message
+	}
+	return byPath
+}
+
+func TestConfigValidation(t *testing.T) {
+	t.Run("Catalogs", func(t *testing.T) {
+		var r validate.Report
+		validate.Catalog(&r, "catalog.ok", iceberg.Properties{
+			"type": "sql", "sql.driver": "sqlite3", "sql.dialect": "sqlite",
+		})
+		validate.Catalog(&r, "catalog.by-uri", iceberg.Properties{"uri": "https://catalog.example.com"})
+		require.NoError(t, r.Err())
+
+		validate.Catalog(&r, "catalog.untyped", iceberg.Properties{"warehouse": "s3://w/"})
+		validate.Catalog(&r, "catalog.hive", iceberg.Properties{"type": "hive"})
+		validate.Catalog(&r, "catalog.rest", iceberg.Properties{"type": "rest"})
+		validate.Catalog(&r, "catalog.sql", iceberg.Properties{"type": "sql", "sql.driver": "nope", "sql.dialect": "db2"})
+		validate.Catalog(&r, "catalog.bare", iceberg.Properties{"type": "sql"})
+
+		got := problems(t, r.Err())
+		assert.Equal(t, "is required", got["catalog.untyped.type"])
+		assert.Contains(t, got["catalog.hive.type"], `unknown catalog type "hive"`)
+		assert.Contains(t, got["catalog.rest.uri"], "is required for rest catalogs")
+		assert.Contains(t, got["catalog.sql.sql.driver"], `"nope" is not compiled in`)
+		assert.Contains(t, got["catalog.sql.sql.dialect"], `unsupported sql dialect "db2"`)
+		assert.Contains(t, got["catalog.bare.sql.driver"], "is required")
+		assert.Contains(t, got["catalog.bare.sql.dialect"], "is required")
+		assert.Len(t, got, 7, "every problem is reported")
+	})
+
+	t.Run("Ports", func(t *testing.T) {
+		var r validate.Report
+		validate.Port(&r, "port", 8080)
+		require.NoError(t, r.Err())
+		validate.Port(&r, "port", 0)
+		validate.Port(&r, "admin.port", 70000)
+		got := problems(t, r.Err())
+		assert.Contains(t, got["port"], "out of range")
+		assert.Contains(t, got["admin.port"], "out of range")
+	})
+
+	t.Run("Writable", func(t *testing.T) {
+		dir := t.TempDir()
+		existing := filepath.Join(dir, "app.log")
+		require.NoError(t, os.WriteFile(existing, []byte("kept\n"), 0o600))
+		readOnly := filepath.Join(dir, "ro.log")
+		require.NoError(t, os.WriteFile(readOnly, nil, 0o400))
+		blocker := filepath.Join(dir, "file")
+		require.NoError(t, os.WriteFile(blocker, nil, 0o600))
+
+		var r validate.Report
+		validate.Writable(&r, "existing", existing)
+		validate.Writable(&r, "nested", filepath.Join(dir, "logs", "2026", "app.log"))
+		require.NoError(t, r.Err())
+
+		data, err := os.ReadFile(existing)
+		require.NoError(t, err)
+		assert.Equal(t, "kept\n", string(data), "checking doesn't change the file")
+		_, err = os.Stat(filepath.Join(dir, "logs"))
+		assert.True(t, os.IsNotExist(err), "checking doesn't create directories")
+
+		validate.Writable(&r, "dir", dir)
+		validate.Writable(&r, "under-file", filepath.Join(blocker, "app.log"))
+		validate.Writable(&r, "empty", "")
+		if os.Geteuid() != 0 {
+			validate.Writable(&r, "read-only", readOnly)
+		}
+		got := problems(t, r.Err())
+		assert.Contains(t, got["dir"], "is a directory")
+		assert.Contains(t, got["under-file"], "is not a directory")
+		assert.Equal(t, "is required", got["empty"])
+		if os.Geteuid() != 0 {
+			assert.Contains(t, got["read-only"], "not writable")
+		}
+
+		r = validate.NewReport(validate.SkipFilesystem())
+		validate.Writable(&r, "under-file", filepath.Join(blocker, "app.log"))
+		validate.Writable(&r, "empty", "")
+		assert.Equal(t, map[string]string{"empty": "is required"}, problems(t, r.Err()))
+	})
+
+	t.Run("Sections", func(t *testing.T) {
+		log := &logger.Config{
+			Format: "xml",
+			Outputs: []logger.Output{
+				{Type: logger.OutputStdout},
+				{Type: "syslog"},
+				{Type: logger.OutputFile},
+			},
+			AccessOutputs: []logger.Output{{Type: logger.OutputStderr, Format: "yaml"}},
+		}
+		compression := middleware.CompressionConfig{Encodings: []string{"zstd", "br"}}
+
+		var r validate.Report
+		r.Merge("log", log.Validate())
+		r.Merge("compression", compression.Validate())
+		r.Merge("tracing", errors.New("plain error"))
+
+		got := problems(t, r.Err())
+		assert.Equal(t, map[string]string{
+			"log.format":                   `unknown log format "xml"`,
+			"log.outputs[1].type":          `unknown log output type "syslog"`,
+			"log.outputs[2].file-name":     "is required",
+			"log.access-outputs[0].format": `unknown log format "yaml"`,
+			"compression.encodings[1]":     `unsupported encoding "br"`,
+			"tracing":                      "plain error",
+		}, got)
+		assert.Contains(t, r.Err().Error(), "log.outputs[1].type: unknown log output type \"syslog\"\n")
+	})
+}
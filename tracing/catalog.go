@@ -0,0 +1,162 @@
+package tracing
+
+import (
+	"context"
+	"iter"
+	"strings"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/apache/iceberg-go/table"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedCatalog starts a span around every backend call. The span context is
+// passed on, so backends and FileIO implementations that are instrumented
+// themselves nest their spans below it.
+type tracedCatalog struct {
+	catalog.Catalog
+}
+
+func InstrumentCatalog(cat catalog.Catalog) catalog.Catalog {
+	return &tracedCatalog{Catalog: cat}
+}
+
+func start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, "catalog."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func ident(key string, ident table.Identifier) attribute.KeyValue {
+	return attribute.String(key, strings.Join(ident, "."))
+}
+
+func (c *tracedCatalog) CreateTable(ctx context.Context, identifier table.Identifier, schema *iceberg.Schema, opts ...catalog.CreateTableOpt) (*table.Table, error) {
+	ctx, span := start(ctx, "CreateTable", ident("iceberg.table", identifier))
+	tbl, err := c.Catalog.CreateTable(ctx, identifier, schema, opts...)
+	if err == nil {
+		span.SetAttributes(attribute.String("iceberg.metadata_location", tbl.MetadataLocation()))
+	}
+	end(span, err)
+	return tbl, err
+}
+
+func (c *tracedCatalog) CommitTable(ctx context.Context, tbl *table.Table, reqs []table.Requirement, updates []table.Update) (table.Metadata, string, error) {
+	ctx, span := start(ctx, "CommitTable",
+		ident("iceberg.table", tbl.Identifier()),
+		attribute.Int("iceberg.requirements", len(reqs)),
+		attribute.Int("iceberg.updates", len(updates)))
+	metadata, metadataLoc, err := c.Catalog.CommitTable(ctx, tbl, reqs, updates)
+	if err == nil {
+		span.SetAttributes(attribute.String("iceberg.metadata_location", metadataLoc))
+	}
+	end(span, err)
+	return metadata, metadataLoc, err
+}
+
+func (c *tracedCatalog) ListTables(ctx context.Context, namespace table.Identifier) iter.Seq2[table.Identifier, error] {
+	return func(yield func(table.Identifier, error) bool) {
+		ctx, span := start(ctx, "ListTables", ident("iceberg.namespace", namespace))
+		var (
+			iterErr error
+			count   int
+		)
+		for ident, err := range c.Catalog.ListTables(ctx, namespace) {
+			if err != nil {
+				iterErr = err
+			} else {
+				count++
+			}
+			if !yield(ident, err) {
+				break
+			}
+		}
+		span.SetAttributes(attribute.Int("iceberg.tables", count))
+		end(span, iterErr)
+	}
+}
+
+func (c *tracedCatalog) LoadTable(ctx context.Context, identifier table.Identifier, props iceberg.Properties) (*table.Table, error) {
+	ctx, span := start(ctx, "LoadTable", ident("iceberg.table", identifier))
+	tbl, err := c.Catalog.LoadTable(ctx, identifier, props)
+	if err == nil {
+		span.SetAttributes(attribute.String("iceberg.metadata_location", tbl.MetadataLocation()))
+	}
+	end(span, err)
+	return tbl, err
+}
+
+func (c *tracedCatalog) DropTable(ctx context.Context, identifier table.Identifier) error {
+	ctx, span := start(ctx, "DropTable", ident("iceberg.table", identifier))
+	err := c.Catalog.DropTable(ctx, identifier)
+	end(span, err)
+	return err
+}
+
+func (c *tracedCatalog) RenameTable(ctx context.Context, from, to table.Identifier) (*table.Table, error) {
+	ctx, span := start(ctx, "RenameTable", ident("iceberg.table", from), ident("iceberg.destination", to))
+	tbl, err := c.Catalog.RenameTable(ctx, from, to)
+	end(span, err)
+	return tbl, err
+}
+
+func (c *tracedCatalog) CheckTableExists(ctx context.Context, identifier table.Identifier) (bool, error) {
+	ctx, span := start(ctx, "CheckTableExists", ident("iceberg.table", identifier))
+	exists, err := c.Catalog.CheckTableExists(ctx, identifier)
+	end(span, err)
+	return exists, err
+}
+
+func (c *tracedCatalog) ListNamespaces(ctx context.Context, parent table.Identifier) ([]table.Identifier, error) {
+	ctx, span := start(ctx, "ListNamespaces", ident("iceberg.namespace", parent))
+	namespaces, err := c.Catalog.ListNamespaces(ctx, parent)
+	end(span, err)
+	return namespaces, err
+}
+
+func (c *tracedCatalog) CreateNamespace(ctx context.Context, namespace table.Identifier, props iceberg.Properties) error {
+	ctx, span := start(ctx, "CreateNamespace", ident("iceberg.namespace", namespace))
+	err := c.Catalog.CreateNamespace(ctx, namespace, props)
+	end(span, err)
+	return err
+}
+
+func (c *tracedCatalog) DropNamespace(ctx context.Context, namespace table.Identifier) error {
+	ctx, span := start(ctx, "DropNamespace", ident("iceberg.namespace", namespace))
+	err := c.Catalog.DropNamespace(ctx, namespace)
+	end(span, err)
+	return err
+}
+
+func (c *tracedCatalog) CheckNamespaceExists(ctx context.Context, namespace table.Identifier) (bool, error) {
+	ctx, span := start(ctx, "CheckNamespaceExists", ident("iceberg.namespace", namespace))
+	exists, err := c.Catalog.CheckNamespaceExists(ctx, namespace)
+	end(span, err)
+	return exists, err
+}
+
+func (c *tracedCatalog) LoadNamespaceProperties(ctx context.Context, namespace table.Identifier) (iceberg.Properties, error) {
+	ctx, span := start(ctx, "LoadNamespaceProperties", ident("iceberg.namespace", namespace))
+	props, err := c.Catalog.LoadNamespaceProperties(ctx, namespace)
+	end(span, err)
+	return props, err
+}
+
+func (c *tracedCatalog) UpdateNamespaceProperties(ctx context.Context, namespace table.Identifier, removals []string, updates iceberg.Properties) (catalog.PropertiesUpdateSummary, error) {
+	ctx, span := start(ctx, "UpdateNamespaceProperties", ident("iceberg.namespace", namespace))
+	summary, err := c.Catalog.UpdateNamespaceProperties(ctx, namespace, removals, updates)
+	end(span, err)
+	return summary, err
+}
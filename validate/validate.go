@@ -0,0 +1,226 @@
+// Package validate collects config problems so that all of them are reported
+// at once, each with the YAML path of the key at fault.
+package validate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/apache/iceberg-go"
+	"github.com/apache/iceberg-go/catalog"
+	"github.com/xixipi-lining/iceberg-rest-catalog/secret"
+)
+
+// Problem is one invalid key.
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+	return p.Path + ": " + p.Message
+}
+
+// Errors lists every problem found, one per line.
+type Errors []Problem
+
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i, p := range e {
+		lines[i] = p.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Report collects problems. The zero value is ready to use and runs every
+// check.
+type Report struct {
+	problems       Errors
+	skipFilesystem bool
+}
+
+// Option changes which checks a Report runs.
+type Option func(*Report)
+
+// SkipFilesystem leaves out the checks against the local file system, for a
+// config checked on another machine than the one it is deployed to. Files
+// still have to be named.
+func SkipFilesystem() Option {
+	return func(r *Report) {
+		r.skipFilesystem = true
+	}
+}
+
+// NewReport returns a Report running the checks opts select.
+func NewReport(opts ...Option) Report {
+	var r Report
+	for _, opt := range opts {
+		opt(&r)
+	}
+	return r
+}
+
+// Add records a problem with the key at path.
+func (r *Report) Add(path, format string, args ...any) {
+	r.problems = append(r.problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Merge records the problems in err, returned by the validation of the
+// section at path. Their paths are taken relative to it.
+func (r *Report) Merge(path string, err error) {
+	if err == nil {
+		return
+	}
+	var errs Errors
+	if !errors.As(err, &errs) {
+		r.problems = append(r.problems, Problem{Path: path, Message: err.Error()})
+		return
+	}
+	for _, p := range errs {
+		r.problems = append(r.problems, Problem{Path: Join(path, p.Path), Message: p.Message})
+	}
+}
+
+// Err returns the problems recorded, or nil if there are none.
+func (r *Report) Err() error {
+	if len(r.problems) == 0 {
+		return nil
+	}
+	return r.problems
+}
+
+// Join appends key to the path of its parent. Keys starting with "[" index a
+// list.
+func Join(parent, key string) string {
+	if parent == "" || key == "" || strings.HasPrefix(key, "[") {
+		return parent + key
+	}
+	return parent + "." + key
+}
+
+// Index returns the path of the i-th item of the list at path.
+func Index(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+// Port checks that port can be listened on.
+func Port(r *Report, path string, port int) {
+	if port < 1 || port > 65535 {
+		r.Add(path, "%d is out of range 1-65535", port)
+	}
+}
+
+// Writable checks that file can be written, creating it if needed, without
+// changing it. With SkipFilesystem, it only checks that file is named.
+func Writable(r *Report, path, file string) {
+	if file == "" {
+		r.Add(path, "is required")
+		return
+	}
+	if r.skipFilesystem {
+		return
+	}
+	if info, err := os.Stat(file); err == nil {
+		if info.IsDir() {
+			r.Add(path, "%s is a directory", file)
+			return
+		}
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			r.Add(path, "%s is not writable: %s", file, unwrapPath(err))
+			return
+		}
+		_ = f.Close()
+		return
+	}
+
+	// Log files are created along with their directory.
+	dir := filepath.Dir(file)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				r.Add(path, "%s is not a directory", dir)
+				return
+			}
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	f, err := os.CreateTemp(dir, ".validate-*")
+	if err != nil {
+		r.Add(path, "can't create %s: %s", file, unwrapPath(err))
+		return
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
+
+func unwrapPath(err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
+}
+
+// requiredProperties lists, per catalog type, the properties loading fails
+// without.
+var requiredProperties = map[string][]string{
+	"sql":   {"sql.driver", "sql.dialect"},
+	"rest":  {"uri"},
+	"http":  {"uri"},
+	"https": {"uri"},
+}
+
+var sqlDialects = []string{"postgres", "mysql", "sqlite", "mssql", "oracle"}
+
+// Catalog checks that the catalog type of props is registered and that the
+// properties it requires are set, without connecting to it. Values holding
+// secret references are only known once resolved and aren't checked further.
+func Catalog(r *Report, path string, props iceberg.Properties) {
+	catalogType := props["type"]
+	if catalogType == "" {
+		if uri, err := url.Parse(props["uri"]); err == nil && strings.Contains(props["uri"], "://") {
+			catalogType = uri.Scheme
+		}
+	}
+	if catalogType == "" {
+		r.Add(Join(path, "type"), "is required")
+		return
+	}
+	registered := catalog.GetRegisteredCatalogs()
+	if !slices.Contains(registered, catalogType) {
+		slices.Sort(registered)
+		r.Add(Join(path, "type"), "unknown catalog type %q, expected one of %s", catalogType, strings.Join(registered, ", "))
+		return
+	}
+
+	for _, key := range requiredProperties[catalogType] {
+		if props[key] == "" {
+			r.Add(Join(path, key), "is required for %s catalogs", catalogType)
+		}
+	}
+	if catalogType != "sql" {
+		return
+	}
+	if driver := props["sql.driver"]; driver != "" && !secret.HasReference(driver) && !slices.Contains(sql.Drivers(), driver) {
+		r.Add(Join(path, "sql.driver"), "sql driver %q is not compiled in, expected one of %s", driver, strings.Join(sql.Drivers(), ", "))
+	}
+	if dialect := strings.ToLower(props["sql.dialect"]); dialect != "" && !secret.HasReference(dialect) && !slices.Contains(sqlDialects, dialect) {
+		r.Add(Join(path, "sql.dialect"), "unsupported sql dialect %q, expected one of %s", dialect, strings.Join(sqlDialects, ", "))
+	}
+}